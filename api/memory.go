@@ -0,0 +1,60 @@
+package api
+
+import "github.com/juleur/communes-francaises/providers"
+
+// memoryStore answers queries against a slice of communes decoded entirely
+// into memory, with a few indexes built once at load time. Near falls back
+// to a brute-force Haversine scan: at ~35k communes that is fast enough
+// without needing the SQLite store's R-Tree index.
+type memoryStore struct {
+	communes      []providers.Commune
+	byCodeInsee   map[string]providers.Commune
+	byCodePostal  map[string][]providers.Commune
+	byDepartement map[string][]providers.Commune
+}
+
+func newMemoryStore(communes []providers.Commune) *memoryStore {
+	s := &memoryStore{
+		communes:      communes,
+		byCodeInsee:   make(map[string]providers.Commune, len(communes)),
+		byCodePostal:  map[string][]providers.Commune{},
+		byDepartement: map[string][]providers.Commune{},
+	}
+	for _, c := range communes {
+		s.byCodeInsee[c.CodeInsee] = c
+		for _, codePostal := range c.CodesPostaux {
+			s.byCodePostal[codePostal] = append(s.byCodePostal[codePostal], c)
+		}
+		if c.CodeDepartement != "" {
+			s.byDepartement[c.CodeDepartement] = append(s.byDepartement[c.CodeDepartement], c)
+		}
+	}
+	return s
+}
+
+func (s *memoryStore) ByCodeInsee(codeInsee string) (providers.Commune, bool, error) {
+	c, ok := s.byCodeInsee[codeInsee]
+	return c, ok, nil
+}
+
+func (s *memoryStore) ByCodePostal(codePostal string) ([]providers.Commune, error) {
+	return s.byCodePostal[codePostal], nil
+}
+
+func (s *memoryStore) ByDepartement(codeDepartement string) ([]providers.Commune, error) {
+	return s.byDepartement[codeDepartement], nil
+}
+
+func (s *memoryStore) Near(lat, lon, radiusMeters float64) ([]providers.Commune, error) {
+	var found []providers.Commune
+	for _, c := range s.communes {
+		if len(c.Location.Coordinates) != 2 {
+			continue
+		}
+		d := haversineMeters(lat, lon, c.Location.Coordinates[0], c.Location.Coordinates[1])
+		if d <= radiusMeters {
+			found = append(found, c)
+		}
+	}
+	return found, nil
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/juleur/communes-francaises/providers"
+)
+
+var (
+	errNotFound      = errors.New("commune not found")
+	errMissingFilter = errors.New("one of codePostal or departement is required")
+	errBadLatLon     = errors.New("lat and lon must be valid floats")
+	errBadRadius     = errors.New("radius must be a valid float, in meters")
+)
+
+// writeCommunes content-negotiates the response body: "?format=geojson" or
+// an Accept header of "application/geo+json" returns a GeoJSON
+// FeatureCollection, anything else returns the plain Commune JSON array.
+func writeCommunes(w http.ResponseWriter, r *http.Request, communes []providers.Commune) {
+	if wantsGeoJSON(r) {
+		writeGeoJSON(w, communes)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(communes)
+}
+
+func wantsGeoJSON(r *http.Request) bool {
+	if r.URL.Query().Get("format") == "geojson" {
+		return true
+	}
+	return strings.Contains(r.Header.Get("Accept"), "geo+json")
+}
+
+type featureCollection struct {
+	Type     string    `json:"type"`
+	Features []feature `json:"features"`
+}
+
+type feature struct {
+	Type       string             `json:"type"`
+	Properties featureProperties  `json:"properties"`
+	Geometry   providers.Location `json:"geometry"`
+}
+
+type featureProperties struct {
+	Nom             string   `json:"nom"`
+	CodeInsee       string   `json:"codeInsee"`
+	Departement     string   `json:"departement,omitempty"`
+	CodeDepartement string   `json:"codeDepartement,omitempty"`
+	Region          string   `json:"region,omitempty"`
+	CodesPostaux    []string `json:"codesPostaux"`
+}
+
+func writeGeoJSON(w http.ResponseWriter, communes []providers.Commune) {
+	fc := featureCollection{Type: "FeatureCollection", Features: make([]feature, 0, len(communes))}
+	for _, c := range communes {
+		fc.Features = append(fc.Features, feature{
+			Type: "Feature",
+			Properties: featureProperties{
+				Nom:             c.Nom,
+				CodeInsee:       c.CodeInsee,
+				Departement:     c.Departement,
+				CodeDepartement: c.CodeDepartement,
+				Region:          c.Region,
+				CodesPostaux:    c.CodesPostaux,
+			},
+			Geometry: c.Location,
+		})
+	}
+	w.Header().Set("Content-Type", "application/geo+json")
+	json.NewEncoder(w).Encode(fc)
+}
@@ -0,0 +1,154 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/juleur/communes-francaises/providers"
+)
+
+var requestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "communes_api_requests_total",
+	Help: "Total HTTP requests handled by the communes API, by route and status class.",
+}, []string{"route", "status"})
+
+// NewRouter builds the chi router for store: /communes/{insee}, a
+// /communes listing filterable by codePostal or departement, a
+// /communes/near radius search, plus /healthz and /metrics.
+func NewRouter(store Store) chi.Router {
+	r := chi.NewRouter()
+	r.Use(middleware.Logger)
+	r.Use(middleware.Recoverer)
+
+	r.Get("/healthz", handleHealthz)
+	r.Get("/metrics", promhttp.Handler().ServeHTTP)
+
+	r.Route("/communes", func(r chi.Router) {
+		r.Get("/", trackRoute("communes_list", handleList(store)))
+		r.Get("/near", trackRoute("communes_near", handleNear(store)))
+		r.Get("/{insee}", trackRoute("communes_by_insee", handleByInsee(store)))
+	})
+
+	return r
+}
+
+func handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok"))
+}
+
+func handleByInsee(store Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		insee := chi.URLParam(r, "insee")
+		commune, found, err := store.ByCodeInsee(insee)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if !found {
+			writeError(w, http.StatusNotFound, errNotFound)
+			return
+		}
+		writeCommunes(w, r, []providers.Commune{commune})
+	}
+}
+
+func handleList(store Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		codePostal := r.URL.Query().Get("codePostal")
+		departement := r.URL.Query().Get("departement")
+
+		switch {
+		case codePostal != "":
+			communes, err := store.ByCodePostal(codePostal)
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, err)
+				return
+			}
+			writeCommunes(w, r, communes)
+		case departement != "":
+			communes, err := store.ByDepartement(departement)
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, err)
+				return
+			}
+			writeCommunes(w, r, communes)
+		default:
+			writeError(w, http.StatusBadRequest, errMissingFilter)
+		}
+	}
+}
+
+func handleNear(store Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		lat, err := strconv.ParseFloat(r.URL.Query().Get("lat"), 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, errBadLatLon)
+			return
+		}
+		lon, err := strconv.ParseFloat(r.URL.Query().Get("lon"), 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, errBadLatLon)
+			return
+		}
+		radius := 1000.0
+		if raw := r.URL.Query().Get("radius"); raw != "" {
+			radius, err = strconv.ParseFloat(raw, 64)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, errBadRadius)
+				return
+			}
+		}
+
+		communes, err := store.Near(lat, lon, radius)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeCommunes(w, r, communes)
+	}
+}
+
+// trackRoute wraps h so every response increments requestsTotal labeled by
+// route and status class (2xx/4xx/5xx).
+func trackRoute(route string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		h(sw, r)
+		requestsTotal.WithLabelValues(route, statusClass(sw.status)).Inc()
+	}
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func statusClass(status int) string {
+	switch {
+	case status >= 500:
+		return "5xx"
+	case status >= 400:
+		return "4xx"
+	default:
+		return "2xx"
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
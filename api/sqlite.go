@@ -0,0 +1,160 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"math"
+
+	"github.com/juleur/communes-francaises/providers"
+)
+
+// cosDegrees is math.Cos for an angle given in degrees.
+func cosDegrees(degrees float64) float64 {
+	return math.Cos(degrees * math.Pi / 180)
+}
+
+// sqliteStore answers queries against the database written by the
+// exporter's sqlite output encoder, using the communes_rtree virtual table
+// to bound Near before refining with an exact Haversine check.
+type sqliteStore struct {
+	db *sql.DB
+}
+
+func (s *sqliteStore) ByCodeInsee(codeInsee string) (providers.Commune, bool, error) {
+	row := s.db.QueryRow(`SELECT id, code_insee, nom, departement, code_departement, region, population, elevation, timezone, lat, lon, alternate_names_json
+		FROM communes WHERE code_insee = ?`, codeInsee)
+	c, id, err := scanCommune(row)
+	if err == sql.ErrNoRows {
+		return providers.Commune{}, false, nil
+	}
+	if err != nil {
+		return providers.Commune{}, false, err
+	}
+	c.CodesPostaux, err = s.codesPostaux(id)
+	if err != nil {
+		return providers.Commune{}, false, err
+	}
+	return c, true, nil
+}
+
+func (s *sqliteStore) ByCodePostal(codePostal string) ([]providers.Commune, error) {
+	rows, err := s.db.Query(`SELECT c.id, c.code_insee, c.nom, c.departement, c.code_departement, c.region, c.population, c.elevation, c.timezone, c.lat, c.lon, c.alternate_names_json
+		FROM communes c
+		JOIN codes_postaux cp ON cp.commune_id = c.id
+		WHERE cp.code_postal = ?`, codePostal)
+	if err != nil {
+		return nil, err
+	}
+	return s.scanAll(rows)
+}
+
+func (s *sqliteStore) ByDepartement(codeDepartement string) ([]providers.Commune, error) {
+	rows, err := s.db.Query(`SELECT id, code_insee, nom, departement, code_departement, region, population, elevation, timezone, lat, lon, alternate_names_json
+		FROM communes WHERE code_departement = ?`, codeDepartement)
+	if err != nil {
+		return nil, err
+	}
+	return s.scanAll(rows)
+}
+
+func (s *sqliteStore) Near(lat, lon, radiusMeters float64) ([]providers.Commune, error) {
+	// One degree of latitude is ~111km everywhere; longitude shrinks with
+	// cos(latitude). This bounding box is intentionally a bit generous -
+	// the Haversine check below trims it down to the exact circle.
+	latDelta := radiusMeters / 111000
+	lonDelta := radiusMeters / (111000 * cosDegrees(lat))
+
+	rows, err := s.db.Query(`SELECT c.id, c.code_insee, c.nom, c.departement, c.code_departement, c.region, c.population, c.elevation, c.timezone, c.lat, c.lon, c.alternate_names_json
+		FROM communes c
+		JOIN communes_rtree r ON r.id = c.id
+		WHERE r.min_lat >= ? AND r.max_lat <= ? AND r.min_lon >= ? AND r.max_lon <= ?`,
+		lat-latDelta, lat+latDelta, lon-lonDelta, lon+lonDelta)
+	if err != nil {
+		return nil, err
+	}
+	candidates, err := s.scanAll(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	var found []providers.Commune
+	for _, c := range candidates {
+		if len(c.Location.Coordinates) != 2 {
+			continue
+		}
+		if haversineMeters(lat, lon, c.Location.Coordinates[0], c.Location.Coordinates[1]) <= radiusMeters {
+			found = append(found, c)
+		}
+	}
+	return found, nil
+}
+
+type scannable interface {
+	Scan(dest ...any) error
+}
+
+func scanCommune(row scannable) (providers.Commune, int64, error) {
+	var (
+		id                                                                 int64
+		codeInsee, nom                                                     string
+		departement, codeDepartement, region, timezone, alternateNamesJSON sql.NullString
+		population, elevation                                              sql.NullInt64
+		lat, lon                                                           sql.NullFloat64
+	)
+	err := row.Scan(&id, &codeInsee, &nom, &departement, &codeDepartement, &region, &population, &elevation, &timezone, &lat, &lon, &alternateNamesJSON)
+	if err != nil {
+		return providers.Commune{}, 0, err
+	}
+
+	c := providers.Commune{
+		CodeInsee:       codeInsee,
+		Nom:             nom,
+		Departement:     departement.String,
+		CodeDepartement: codeDepartement.String,
+		Region:          region.String,
+		Population:      int(population.Int64),
+		Elevation:       int(elevation.Int64),
+		Timezone:        timezone.String,
+	}
+	if lat.Valid && lon.Valid {
+		c.Location = providers.Location{Type: "Point", Coordinates: []float64{lat.Float64, lon.Float64}}
+	}
+	if alternateNamesJSON.Valid {
+		json.Unmarshal([]byte(alternateNamesJSON.String), &c.AlternateNames)
+	}
+	return c, id, nil
+}
+
+func (s *sqliteStore) scanAll(rows *sql.Rows) ([]providers.Commune, error) {
+	defer rows.Close()
+	var communes []providers.Commune
+	for rows.Next() {
+		c, id, err := scanCommune(rows)
+		if err != nil {
+			return nil, err
+		}
+		c.CodesPostaux, err = s.codesPostaux(id)
+		if err != nil {
+			return nil, err
+		}
+		communes = append(communes, c)
+	}
+	return communes, rows.Err()
+}
+
+func (s *sqliteStore) codesPostaux(communeID int64) ([]string, error) {
+	rows, err := s.db.Query(`SELECT code_postal FROM codes_postaux WHERE commune_id = ?`, communeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var codes []string
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, rows.Err()
+}
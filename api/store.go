@@ -0,0 +1,87 @@
+// Package api turns the one-shot exporter into a long-running service: it
+// loads a previously generated JSON or SQLite export and serves it back
+// over HTTP, reusing providers.Commune end-to-end.
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/juleur/communes-francaises/providers"
+)
+
+// Store answers the queries the HTTP handlers need. It is implemented by
+// both an in-memory store (loaded from a JSON/NDJSON export) and a SQLite
+// store (querying the database export directly), so the api subcommand
+// works off of whatever --format the main pipeline last produced.
+type Store interface {
+	ByCodeInsee(codeInsee string) (providers.Commune, bool, error)
+	ByCodePostal(codePostal string) ([]providers.Commune, error)
+	// ByDepartement filters on providers.Commune.CodeDepartement (e.g.
+	// "75"), not the department name.
+	ByDepartement(codeDepartement string) ([]providers.Commune, error)
+	Near(lat, lon, radiusMeters float64) ([]providers.Commune, error)
+}
+
+// earthRadiusMeters is the mean Earth radius used by the Haversine formula.
+const earthRadiusMeters = 6371000
+
+// haversineMeters returns the great-circle distance between two
+// lat/lon points in meters.
+func haversineMeters(lat1, lon1, lat2, lon2 float64) float64 {
+	toRad := math.Pi / 180
+	dLat := (lat2 - lat1) * toRad
+	dLon := (lon2 - lon1) * toRad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*toRad)*math.Cos(lat2*toRad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusMeters * math.Asin(math.Sqrt(a))
+}
+
+// LoadStore opens path and returns the Store best suited to it: a SQLite
+// store for .db/.sqlite files, an in-memory store built from the decoded
+// communes for anything else (the "json" and "ndjson" exporter formats).
+func LoadStore(path string) (Store, error) {
+	if strings.HasSuffix(path, ".db") || strings.HasSuffix(path, ".sqlite") {
+		return newSQLiteStore(path)
+	}
+	return newMemoryStoreFromFile(path)
+}
+
+func newSQLiteStore(path string) (*sqliteStore, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	return &sqliteStore{db: db}, nil
+}
+
+func newMemoryStoreFromFile(path string) (*memoryStore, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var communes []providers.Commune
+	if strings.HasSuffix(path, ".ndjson") {
+		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+			if line == "" {
+				continue
+			}
+			var c providers.Commune
+			if err := json.Unmarshal([]byte(line), &c); err != nil {
+				return nil, fmt.Errorf("api: decode ndjson line: %w", err)
+			}
+			communes = append(communes, c)
+		}
+	} else if err := json.Unmarshal(data, &communes); err != nil {
+		return nil, fmt.Errorf("api: decode %s: %w", path, err)
+	}
+
+	return newMemoryStore(communes), nil
+}
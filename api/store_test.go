@@ -0,0 +1,163 @@
+package api
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/juleur/communes-francaises/output"
+	"github.com/juleur/communes-francaises/providers"
+)
+
+// testCommunes gives both backends the same fixture: Paris and Lyon are a
+// few hundred kilometers apart (outside any radius test below), while
+// Boulogne-Billancourt sits a few kilometers from Paris, inside it.
+// Sarcelles carries no coordinates at all, which both Near implementations
+// must skip rather than match or error on.
+func testCommunes() []providers.Commune {
+	return []providers.Commune{
+		{
+			CodeInsee: "75056", Nom: "Paris", Departement: "Paris", CodeDepartement: "75",
+			CodesPostaux: []string{"75001", "75002"},
+			Location:     providers.Location{Type: "Point", Coordinates: []float64{48.8566, 2.3522}},
+		},
+		{
+			CodeInsee: "92012", Nom: "Boulogne-Billancourt", Departement: "Hauts-de-Seine", CodeDepartement: "92",
+			CodesPostaux: []string{"92100"},
+			Location:     providers.Location{Type: "Point", Coordinates: []float64{48.8352, 2.2410}},
+		},
+		{
+			CodeInsee: "69123", Nom: "Lyon", Departement: "Rhône", CodeDepartement: "69",
+			CodesPostaux: []string{"69001"},
+			Location:     providers.Location{Type: "Point", Coordinates: []float64{45.7640, 4.8357}},
+		},
+		{
+			CodeInsee: "95588", Nom: "Sarcelles", Departement: "Val-d'Oise", CodeDepartement: "95",
+			CodesPostaux: []string{"95200"},
+		},
+	}
+}
+
+func sqliteStoreFixture(t *testing.T) Store {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "communes.db")
+	enc, err := output.New("sqlite", path)
+	if err != nil {
+		t.Fatalf("output.New(sqlite): %v", err)
+	}
+	for _, c := range testCommunes() {
+		if err := enc.Write(c); err != nil {
+			t.Fatalf("Write(%s): %v", c.CodeInsee, err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	store, err := LoadStore(path)
+	if err != nil {
+		t.Fatalf("LoadStore: %v", err)
+	}
+	return store
+}
+
+func memoryStoreFixture(t *testing.T) Store {
+	t.Helper()
+	return newMemoryStore(testCommunes())
+}
+
+func TestStoreByCodeInsee(t *testing.T) {
+	for name, store := range map[string]Store{
+		"memory": memoryStoreFixture(t),
+		"sqlite": sqliteStoreFixture(t),
+	} {
+		t.Run(name, func(t *testing.T) {
+			got, ok, err := store.ByCodeInsee("75056")
+			if err != nil {
+				t.Fatalf("ByCodeInsee: %v", err)
+			}
+			if !ok || got.Nom != "Paris" {
+				t.Errorf("ByCodeInsee(75056) = %+v, ok=%v, want Paris", got, ok)
+			}
+
+			_, ok, err = store.ByCodeInsee("00000")
+			if err != nil {
+				t.Fatalf("ByCodeInsee(unknown): %v", err)
+			}
+			if ok {
+				t.Error("ByCodeInsee(unknown) should report ok=false")
+			}
+		})
+	}
+}
+
+func TestStoreByCodePostal(t *testing.T) {
+	for name, store := range map[string]Store{
+		"memory": memoryStoreFixture(t),
+		"sqlite": sqliteStoreFixture(t),
+	} {
+		t.Run(name, func(t *testing.T) {
+			got, err := store.ByCodePostal("75002")
+			if err != nil {
+				t.Fatalf("ByCodePostal: %v", err)
+			}
+			if len(got) != 1 || got[0].CodeInsee != "75056" {
+				t.Errorf("ByCodePostal(75002) = %+v, want [Paris]", got)
+			}
+		})
+	}
+}
+
+func TestStoreByDepartement(t *testing.T) {
+	for name, store := range map[string]Store{
+		"memory": memoryStoreFixture(t),
+		"sqlite": sqliteStoreFixture(t),
+	} {
+		t.Run(name, func(t *testing.T) {
+			got, err := store.ByDepartement("75")
+			if err != nil {
+				t.Fatalf("ByDepartement: %v", err)
+			}
+			if len(got) != 1 || got[0].CodeInsee != "75056" {
+				t.Errorf("ByDepartement(75) = %+v, want [Paris]", got)
+			}
+
+			// "Paris" the department name must not match - the field is
+			// keyed by code departement, not by name.
+			got, err = store.ByDepartement("Paris")
+			if err != nil {
+				t.Fatalf("ByDepartement(Paris): %v", err)
+			}
+			if len(got) != 0 {
+				t.Errorf("ByDepartement(Paris) = %+v, want no matches for a department name", got)
+			}
+		})
+	}
+}
+
+func TestStoreNear(t *testing.T) {
+	for name, store := range map[string]Store{
+		"memory": memoryStoreFixture(t),
+		"sqlite": sqliteStoreFixture(t),
+	} {
+		t.Run(name, func(t *testing.T) {
+			// 10km around Paris should pick up Boulogne-Billancourt but not
+			// Lyon (~390km away) or Sarcelles (no coordinates at all).
+			got, err := store.Near(48.8566, 2.3522, 10000)
+			if err != nil {
+				t.Fatalf("Near: %v", err)
+			}
+			codes := map[string]bool{}
+			for _, c := range got {
+				codes[c.CodeInsee] = true
+			}
+			if !codes["75056"] || !codes["92012"] {
+				t.Errorf("Near(Paris, 10km) = %+v, want Paris and Boulogne-Billancourt", got)
+			}
+			if codes["69123"] {
+				t.Errorf("Near(Paris, 10km) should not include Lyon: %+v", got)
+			}
+			if len(got) != 2 {
+				t.Errorf("Near(Paris, 10km) returned %d communes, want 2", len(got))
+			}
+		})
+	}
+}
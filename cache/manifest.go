@@ -0,0 +1,110 @@
+package cache
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// flushInterval is how many Mark* calls accumulate in memory before they
+// are batched into a single rewrite of manifest.json. At ~35k communes,
+// saving the whole map on every call would otherwise dominate a
+// rate-limited fetch with O(n^2) disk I/O.
+const flushInterval = 200
+
+// Manifest tracks which codes a job has already processed, so a killed run
+// resumed with --resume does not redo work, and which codes failed, so a
+// later pass can retry just those.
+type Manifest struct {
+	path  string
+	mu    sync.Mutex
+	dirty int
+
+	Done   map[string]bool `json:"done"`
+	Failed map[string]bool `json:"failed"`
+}
+
+// LoadManifest reads the manifest at dir/manifest.json, returning an empty
+// one if it does not exist yet.
+func LoadManifest(dir string) (*Manifest, error) {
+	m := &Manifest{
+		path:   filepath.Join(dir, "manifest.json"),
+		Done:   map[string]bool{},
+		Failed: map[string]bool{},
+	}
+	data, err := os.ReadFile(m.path)
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// MarkDone records codeInsee as successfully fetched and clears it from the
+// failed set. The manifest is only rewritten to disk every flushInterval
+// marks; call Flush once the run is done to persist the remainder.
+func (m *Manifest) MarkDone(codeInsee string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Done[codeInsee] = true
+	delete(m.Failed, codeInsee)
+	return m.markDirty()
+}
+
+// MarkFailed records codeInsee as having failed this run, for later retry.
+// Like MarkDone, it batches its disk writes; call Flush when done.
+func (m *Manifest) MarkFailed(codeInsee string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Failed[codeInsee] = true
+	return m.markDirty()
+}
+
+// IsDone reports whether codeInsee was already fetched successfully.
+func (m *Manifest) IsDone(codeInsee string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.Done[codeInsee]
+}
+
+// Flush persists any marks not yet written to disk. Callers should flush
+// once the fetch pass is over so a run that ends short of a flushInterval
+// boundary does not lose its last batch of progress.
+func (m *Manifest) Flush() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.dirty == 0 {
+		return nil
+	}
+	return m.save()
+}
+
+// markDirty must be called with mu held. It saves immediately only once
+// every flushInterval marks, to keep the full-map rewrite from dominating
+// a large fetch's runtime.
+func (m *Manifest) markDirty() error {
+	m.dirty++
+	if m.dirty < flushInterval {
+		return nil
+	}
+	return m.save()
+}
+
+// save must be called with mu held.
+func (m *Manifest) save() error {
+	data, err := json.MarshalIndent(m, "", " ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(m.path, data, 0644); err != nil {
+		return err
+	}
+	m.dirty = 0
+	return nil
+}
@@ -0,0 +1,89 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestManifestMarkDoneAndFailed(t *testing.T) {
+	dir := t.TempDir()
+	m, err := LoadManifest(dir)
+	if err != nil {
+		t.Fatalf("LoadManifest: %v", err)
+	}
+
+	if m.IsDone("75056") {
+		t.Fatal("a fresh manifest should not report any code as done")
+	}
+	if err := m.MarkFailed("75056"); err != nil {
+		t.Fatalf("MarkFailed: %v", err)
+	}
+	if m.IsDone("75056") {
+		t.Fatal("a failed code should not be reported as done")
+	}
+	if err := m.MarkDone("75056"); err != nil {
+		t.Fatalf("MarkDone: %v", err)
+	}
+	if !m.IsDone("75056") {
+		t.Fatal("expected 75056 to be marked done")
+	}
+	if m.Failed["75056"] {
+		t.Error("MarkDone should clear the code from the failed set")
+	}
+}
+
+func TestManifestBatchesFlushesAndFlushPersistsTheRest(t *testing.T) {
+	dir := t.TempDir()
+	m, err := LoadManifest(dir)
+	if err != nil {
+		t.Fatalf("LoadManifest: %v", err)
+	}
+	manifestPath := filepath.Join(dir, "manifest.json")
+
+	for i := 0; i < flushInterval-1; i++ {
+		if err := m.MarkDone(codeForIndex(i)); err != nil {
+			t.Fatalf("MarkDone: %v", err)
+		}
+	}
+	if _, err := os.Stat(manifestPath); !os.IsNotExist(err) {
+		t.Fatalf("manifest.json should not exist yet before flushInterval marks, stat err: %v", err)
+	}
+
+	if err := m.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		t.Fatalf("reading manifest.json after Flush: %v", err)
+	}
+
+	reloaded, err := LoadManifest(dir)
+	if err != nil {
+		t.Fatalf("LoadManifest after Flush: %v", err)
+	}
+	if !reloaded.IsDone(codeForIndex(0)) {
+		t.Error("Flush should have persisted the batched marks to disk")
+	}
+	if len(data) == 0 {
+		t.Error("manifest.json should not be empty after Flush")
+	}
+}
+
+func TestManifestFlushIsNoopWhenClean(t *testing.T) {
+	dir := t.TempDir()
+	m, err := LoadManifest(dir)
+	if err != nil {
+		t.Fatalf("LoadManifest: %v", err)
+	}
+	if err := m.Flush(); err != nil {
+		t.Fatalf("Flush on a clean manifest: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "manifest.json")); !os.IsNotExist(err) {
+		t.Fatalf("Flush on a clean manifest should not write manifest.json, stat err: %v", err)
+	}
+}
+
+func codeForIndex(i int) string {
+	return "code" + string(rune('A'+i%26)) + string(rune('0'+i/26))
+}
@@ -0,0 +1,31 @@
+package cache
+
+import (
+	"fmt"
+	"time"
+)
+
+// namedSchedules maps the handful of refresh cadences this tool actually
+// needs to a TTL. Full cron expressions are out of scope: --refresh is meant
+// to be driven by an external cron job (or systemd timer) that just re-runs
+// the binary, and this TTL decides which cached entries that run should
+// consider due for revalidation.
+var namedSchedules = map[string]time.Duration{
+	"hourly":  time.Hour,
+	"daily":   24 * time.Hour,
+	"weekly":  7 * 24 * time.Hour,
+	"monthly": 30 * 24 * time.Hour,
+}
+
+// ParseSchedule turns a --refresh value into a TTL. It accepts the named
+// cadences above, or anything time.ParseDuration understands (e.g. "72h").
+func ParseSchedule(schedule string) (time.Duration, error) {
+	if ttl, ok := namedSchedules[schedule]; ok {
+		return ttl, nil
+	}
+	ttl, err := time.ParseDuration(schedule)
+	if err != nil {
+		return 0, fmt.Errorf("invalid --refresh schedule %q: %w", schedule, err)
+	}
+	return ttl, nil
+}
@@ -0,0 +1,41 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseScheduleNamed(t *testing.T) {
+	cases := map[string]time.Duration{
+		"hourly":  time.Hour,
+		"daily":   24 * time.Hour,
+		"weekly":  7 * 24 * time.Hour,
+		"monthly": 30 * 24 * time.Hour,
+	}
+	for schedule, want := range cases {
+		got, err := ParseSchedule(schedule)
+		if err != nil {
+			t.Errorf("ParseSchedule(%q): unexpected error: %v", schedule, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("ParseSchedule(%q) = %v, want %v", schedule, got, want)
+		}
+	}
+}
+
+func TestParseScheduleDuration(t *testing.T) {
+	got, err := ParseSchedule("72h")
+	if err != nil {
+		t.Fatalf("ParseSchedule(72h): unexpected error: %v", err)
+	}
+	if got != 72*time.Hour {
+		t.Errorf("ParseSchedule(72h) = %v, want 72h", got)
+	}
+}
+
+func TestParseScheduleInvalid(t *testing.T) {
+	if _, err := ParseSchedule("fortnightly"); err == nil {
+		t.Error("ParseSchedule(fortnightly): expected an error, got nil")
+	}
+}
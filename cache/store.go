@@ -0,0 +1,84 @@
+// Package cache persists fetched communes to disk so a restarted run does
+// not have to re-hit all ~35k geo.api.gouv.fr endpoints. Inspired by
+// wttr.in's peak-prefetch model, each commune is stored as its own JSON
+// file keyed by code INSEE, alongside the validators (ETag/Last-Modified)
+// needed to cheaply check whether the upstream record has changed.
+package cache
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Entry is one cached provider response. Commune is kept as raw JSON rather
+// than a providers.Commune so this package does not depend on providers
+// (which itself depends on cache to wire Store/Manifest into GeoAPIGouvFr).
+type Entry struct {
+	Commune      json.RawMessage `json:"commune"`
+	ETag         string          `json:"etag,omitempty"`
+	LastModified string          `json:"lastModified,omitempty"`
+	FetchedAt    time.Time       `json:"fetchedAt"`
+}
+
+// Store is a JSON-per-code cache rooted at Dir.
+type Store struct {
+	Dir string
+}
+
+// NewStore creates dir (and any missing parents) and returns a Store rooted
+// there.
+func NewStore(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Store{Dir: dir}, nil
+}
+
+func (s *Store) path(codeInsee string) string {
+	return filepath.Join(s.Dir, codeInsee+".json")
+}
+
+// Load reads the cached entry for codeInsee. The second return value is
+// false when there is no cache entry yet.
+func (s *Store) Load(codeInsee string) (Entry, bool, error) {
+	data, err := os.ReadFile(s.path(codeInsee))
+	if os.IsNotExist(err) {
+		return Entry{}, false, nil
+	}
+	if err != nil {
+		return Entry{}, false, err
+	}
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return Entry{}, false, err
+	}
+	return entry, true, nil
+}
+
+// Save writes (or overwrites) the cached entry for codeInsee.
+func (s *Store) Save(codeInsee string, entry Entry) error {
+	data, err := json.MarshalIndent(entry, "", " ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path(codeInsee), data, 0644)
+}
+
+// Has reports whether codeInsee already has a cache entry, without paying
+// the cost of decoding it. --resume uses this to skip codes already done.
+func (s *Store) Has(codeInsee string) bool {
+	_, err := os.Stat(s.path(codeInsee))
+	return err == nil
+}
+
+// Stale reports whether the cached entry for codeInsee is older than ttl.
+// A zero ttl means entries never expire on their own (they still get
+// revalidated via ETag/Last-Modified during --refresh).
+func (e Entry) Stale(ttl time.Duration) bool {
+	if ttl <= 0 {
+		return false
+	}
+	return time.Since(e.FetchedAt) > ttl
+}
@@ -0,0 +1,67 @@
+package cache
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestStoreSaveLoadRoundTrip(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+
+	if _, ok, err := store.Load("75056"); err != nil || ok {
+		t.Fatalf("Load on an empty store: ok=%v err=%v, want ok=false err=nil", ok, err)
+	}
+	if store.Has("75056") {
+		t.Fatal("Has on an empty store should be false")
+	}
+
+	entry := Entry{
+		Commune:      json.RawMessage(`{"nom":"Paris"}`),
+		ETag:         `"abc123"`,
+		LastModified: "Mon, 01 Jan 2024 00:00:00 GMT",
+		FetchedAt:    time.Now(),
+	}
+	if err := store.Save("75056", entry); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	if !store.Has("75056") {
+		t.Fatal("Has after Save should be true")
+	}
+	got, ok, err := store.Load("75056")
+	if err != nil || !ok {
+		t.Fatalf("Load after Save: ok=%v err=%v, want ok=true err=nil", ok, err)
+	}
+	if got.ETag != entry.ETag || got.LastModified != entry.LastModified {
+		t.Errorf("Load() = %+v, want %+v", got, entry)
+	}
+	var gotCommune, wantCommune map[string]string
+	if err := json.Unmarshal(got.Commune, &gotCommune); err != nil {
+		t.Fatalf("unmarshaling Load().Commune: %v", err)
+	}
+	if err := json.Unmarshal(entry.Commune, &wantCommune); err != nil {
+		t.Fatalf("unmarshaling entry.Commune: %v", err)
+	}
+	if gotCommune["nom"] != wantCommune["nom"] {
+		t.Errorf("Load().Commune = %v, want %v", gotCommune, wantCommune)
+	}
+}
+
+func TestEntryStale(t *testing.T) {
+	fresh := Entry{FetchedAt: time.Now()}
+	stale := Entry{FetchedAt: time.Now().Add(-2 * time.Hour)}
+
+	if fresh.Stale(time.Hour) {
+		t.Error("an entry fetched just now should not be stale with a 1h TTL")
+	}
+	if !stale.Stale(time.Hour) {
+		t.Error("an entry fetched 2h ago should be stale with a 1h TTL")
+	}
+	if fresh.Stale(0) || stale.Stale(0) {
+		t.Error("a zero TTL means entries never expire on their own")
+	}
+}
@@ -0,0 +1,72 @@
+package httpclient
+
+import (
+	"sync"
+	"time"
+)
+
+// circuitBreaker trips open after a run of consecutive failures and stays
+// open for cooldown, after which exactly one in-flight probe request is let
+// through to decide whether to close it again (half-open state); every
+// other caller is still rejected until that probe resolves.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+
+	consecutiveFailures int
+	openUntil           time.Time
+	// probing is true while a half-open probe is in flight, so the many
+	// goroutines racing in right after cooldown elapses don't all get
+	// admitted at once - only the one that claims the flag.
+	probing bool
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown}
+}
+
+// Allow reports whether a request may proceed. While open, it admits
+// exactly one caller as the half-open probe once cooldown has elapsed,
+// and rejects everyone else until that probe calls RecordSuccess or
+// RecordFailure.
+func (b *circuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.openUntil.IsZero() {
+		return true
+	}
+	if time.Now().Before(b.openUntil) {
+		return false
+	}
+	if b.probing {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+func (b *circuitBreaker) RecordSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.consecutiveFailures = 0
+	b.openUntil = time.Time{}
+	b.probing = false
+}
+
+func (b *circuitBreaker) RecordFailure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.consecutiveFailures++
+	if b.probing {
+		// The probe itself failed: upstream is still down, reopen
+		// without waiting for the failure count to cross threshold
+		// again.
+		b.openUntil = time.Now().Add(b.cooldown)
+		b.probing = false
+		return
+	}
+	if b.threshold > 0 && b.consecutiveFailures >= b.threshold {
+		b.openUntil = time.Now().Add(b.cooldown)
+	}
+}
@@ -0,0 +1,90 @@
+package httpclient
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerOpensAfterThreshold(t *testing.T) {
+	b := newCircuitBreaker(3, time.Minute)
+	for i := 0; i < 2; i++ {
+		b.RecordFailure()
+		if !b.Allow() {
+			t.Fatalf("breaker should still be closed after %d failures", i+1)
+		}
+	}
+	b.RecordFailure()
+	if b.Allow() {
+		t.Fatal("breaker should be open after reaching the failure threshold")
+	}
+}
+
+func TestCircuitBreakerSuccessResetsFailureCount(t *testing.T) {
+	b := newCircuitBreaker(2, time.Minute)
+	b.RecordFailure()
+	b.RecordSuccess()
+	b.RecordFailure()
+	if !b.Allow() {
+		t.Fatal("a success should reset the consecutive failure count")
+	}
+}
+
+func TestCircuitBreakerHalfOpenAdmitsOneProbe(t *testing.T) {
+	b := newCircuitBreaker(1, 10*time.Millisecond)
+	b.RecordFailure()
+	if b.Allow() {
+		t.Fatal("breaker should be open immediately after tripping")
+	}
+	time.Sleep(15 * time.Millisecond)
+
+	var wg sync.WaitGroup
+	admitted := make(chan bool, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			admitted <- b.Allow()
+		}()
+	}
+	wg.Wait()
+	close(admitted)
+
+	count := 0
+	for a := range admitted {
+		if a {
+			count++
+		}
+	}
+	if count != 1 {
+		t.Fatalf("expected exactly 1 admitted half-open probe, got %d", count)
+	}
+}
+
+func TestCircuitBreakerFailedProbeReopens(t *testing.T) {
+	b := newCircuitBreaker(1, 10*time.Millisecond)
+	b.RecordFailure()
+	time.Sleep(15 * time.Millisecond)
+
+	if !b.Allow() {
+		t.Fatal("expected the half-open probe to be admitted")
+	}
+	b.RecordFailure()
+	if b.Allow() {
+		t.Fatal("breaker should reopen immediately when the probe itself fails")
+	}
+}
+
+func TestCircuitBreakerSuccessfulProbeCloses(t *testing.T) {
+	b := newCircuitBreaker(1, 10*time.Millisecond)
+	b.RecordFailure()
+	time.Sleep(15 * time.Millisecond)
+
+	if !b.Allow() {
+		t.Fatal("expected the half-open probe to be admitted")
+	}
+	b.RecordSuccess()
+	if !b.Allow() {
+		t.Fatal("breaker should be closed after a successful probe")
+	}
+}
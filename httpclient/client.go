@@ -0,0 +1,181 @@
+// Package httpclient wraps net/http with the concerns every caller of
+// geo.api.gouv.fr needs: a QPS/burst rate limit, retry with exponential
+// backoff + jitter on 429/5xx (honoring Retry-After), a circuit breaker, and
+// context cancellation so a Ctrl+C drains in-flight requests instead of
+// piling up more retries.
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// ErrCircuitOpen is returned by Do while the circuit breaker is open, before
+// any request is attempted against upstream.
+var ErrCircuitOpen = errors.New("httpclient: circuit breaker open")
+
+// PermanentError means httpclient gave up because the response itself says
+// retrying won't help (any 4xx other than 429), as opposed to a transient
+// failure (timeout, 429, 5xx, circuit open).
+type PermanentError struct {
+	StatusCode int
+}
+
+func (e *PermanentError) Error() string {
+	return fmt.Sprintf("httpclient: permanent failure, status %d", e.StatusCode)
+}
+
+// IsPermanent reports whether err is a PermanentError.
+func IsPermanent(err error) bool {
+	var permanent *PermanentError
+	return errors.As(err, &permanent)
+}
+
+// Client is a rate-limited, retrying, circuit-breaking http.Client.
+type Client struct {
+	http       *http.Client
+	limiter    *rate.Limiter
+	breaker    *circuitBreaker
+	maxRetries int
+	baseDelay  time.Duration
+	maxDelay   time.Duration
+}
+
+// Option configures a Client built by New.
+type Option func(*Client)
+
+// WithQPS caps the average request rate at qps requests/second, allowing
+// bursts of up to burst requests.
+func WithQPS(qps float64, burst int) Option {
+	return func(c *Client) { c.limiter = rate.NewLimiter(rate.Limit(qps), burst) }
+}
+
+// WithMaxRetries caps how many times a single request is retried after a
+// transient failure.
+func WithMaxRetries(n int) Option {
+	return func(c *Client) { c.maxRetries = n }
+}
+
+// WithBackoff sets the exponential backoff range between retries: the delay
+// doubles from base up to max, plus jitter of up to base.
+func WithBackoff(base, max time.Duration) Option {
+	return func(c *Client) { c.baseDelay = base; c.maxDelay = max }
+}
+
+// WithCircuitBreaker trips the circuit after threshold consecutive
+// failures and keeps it open for cooldown before letting a probe through.
+func WithCircuitBreaker(threshold int, cooldown time.Duration) Option {
+	return func(c *Client) { c.breaker = newCircuitBreaker(threshold, cooldown) }
+}
+
+// WithHTTPClient overrides the underlying http.Client (defaults to
+// http.DefaultClient).
+func WithHTTPClient(h *http.Client) Option {
+	return func(c *Client) { c.http = h }
+}
+
+// New builds a Client with sane defaults: 10 QPS / burst 5, 5 retries,
+// 200ms-10s backoff, breaker trips after 5 consecutive failures for 30s.
+func New(opts ...Option) *Client {
+	c := &Client{
+		http:       http.DefaultClient,
+		limiter:    rate.NewLimiter(10, 5),
+		breaker:    newCircuitBreaker(5, 30*time.Second),
+		maxRetries: 5,
+		baseDelay:  200 * time.Millisecond,
+		maxDelay:   10 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Do executes req, retrying transient failures with exponential backoff and
+// observing ctx between attempts. On success the caller owns closing the
+// response body. On a PermanentError the response is still returned (with
+// its body already closed) so callers can inspect the status code.
+func (c *Client) Do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	var lastErr error
+	for attempt := 0; attempt <= c.maxRetries; attempt++ {
+		if !c.breaker.Allow() {
+			return nil, ErrCircuitOpen
+		}
+		if err := c.limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+
+		resp, err := c.http.Do(req.Clone(ctx))
+		if err != nil {
+			c.breaker.RecordFailure()
+			lastErr = err
+			if !c.waitBackoff(ctx, attempt, 0) {
+				return nil, ctx.Err()
+			}
+			continue
+		}
+
+		if resp.StatusCode < 400 {
+			c.breaker.RecordSuccess()
+			return resp, nil
+		}
+
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"))
+			resp.Body.Close()
+			c.breaker.RecordFailure()
+			lastErr = fmt.Errorf("httpclient: transient status %d", resp.StatusCode)
+			if attempt == c.maxRetries {
+				break
+			}
+			if !c.waitBackoff(ctx, attempt, retryAfter) {
+				return nil, ctx.Err()
+			}
+			continue
+		}
+
+		c.breaker.RecordSuccess()
+		resp.Body.Close()
+		return resp, &PermanentError{StatusCode: resp.StatusCode}
+	}
+	return nil, lastErr
+}
+
+// waitBackoff sleeps for the larger of the server-requested retryAfter and
+// the exponential backoff for attempt, or returns false if ctx ends first.
+func (c *Client) waitBackoff(ctx context.Context, attempt int, retryAfter time.Duration) bool {
+	delay := c.baseDelay << attempt
+	if delay <= 0 || delay > c.maxDelay {
+		delay = c.maxDelay
+	}
+	delay += time.Duration(rand.Int63n(int64(c.baseDelay) + 1))
+	if retryAfter > delay {
+		delay = retryAfter
+	}
+	select {
+	case <-time.After(delay):
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func parseRetryAfter(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if when, err := http.ParseTime(header); err == nil {
+		return time.Until(when)
+	}
+	return 0
+}
@@ -0,0 +1,77 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	cases := []struct {
+		header string
+		want   time.Duration
+	}{
+		{"", 0},
+		{"5", 5 * time.Second},
+		{"not-a-valid-header", 0},
+	}
+	for _, tc := range cases {
+		if got := parseRetryAfter(tc.header); got != tc.want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", tc.header, got, tc.want)
+		}
+	}
+}
+
+func TestParseRetryAfterHTTPDate(t *testing.T) {
+	future := time.Now().Add(30 * time.Second).UTC().Format(http.TimeFormat)
+	got := parseRetryAfter(future)
+	if got <= 0 || got > 30*time.Second {
+		t.Errorf("parseRetryAfter(%q) = %v, want ~30s", future, got)
+	}
+}
+
+func TestWaitBackoffDoublesAndCapsAtMaxDelay(t *testing.T) {
+	c := New(WithBackoff(10*time.Millisecond, 20*time.Millisecond))
+
+	start := time.Now()
+	if !c.waitBackoff(context.Background(), 0, 0) {
+		t.Fatal("waitBackoff should return true when ctx is not done")
+	}
+	elapsed := time.Since(start)
+	// baseDelay<<0 + jitter up to baseDelay: between 10ms and 20ms.
+	if elapsed < 10*time.Millisecond {
+		t.Errorf("waitBackoff returned too early: %v", elapsed)
+	}
+
+	start = time.Now()
+	if !c.waitBackoff(context.Background(), 5, 0) {
+		t.Fatal("waitBackoff should return true when ctx is not done")
+	}
+	elapsed = time.Since(start)
+	// baseDelay<<5 would overflow past maxDelay, so it should be capped.
+	if elapsed > 60*time.Millisecond {
+		t.Errorf("waitBackoff did not cap at maxDelay, took %v", elapsed)
+	}
+}
+
+func TestWaitBackoffHonorsRetryAfter(t *testing.T) {
+	c := New(WithBackoff(time.Millisecond, 5*time.Millisecond))
+
+	start := time.Now()
+	if !c.waitBackoff(context.Background(), 0, 30*time.Millisecond) {
+		t.Fatal("waitBackoff should return true when ctx is not done")
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("waitBackoff should have honored the larger Retry-After delay, took %v", elapsed)
+	}
+}
+
+func TestWaitBackoffReturnsFalseOnContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c := New(WithBackoff(time.Second, time.Second))
+	if c.waitBackoff(ctx, 0, 0) {
+		t.Fatal("waitBackoff should return false once ctx is done")
+	}
+}
@@ -2,250 +2,219 @@ package main
 
 import (
 	"bufio"
-	"encoding/json"
+	"context"
+	"flag"
 	"fmt"
-	"io/ioutil"
 	"log"
-	"math/rand"
 	"net/http"
 	"os"
+	"os/signal"
 	"sort"
-	"strconv"
 	"strings"
-	"sync"
 	"time"
-)
-
-type Commune struct {
-	Nom          string   `json:"nom"`
-	Departement  string   `json:"departement,omitempty"`
-	Region       string   `json:"region,omitempty"`
-	CodesPostaux []string `json:"codesPostaux"`
-	Location     Location `json:"location"`
-}
-
-type Location struct {
-	Type        string    `json:"type"`
-	Coordinates []float64 `json:"coordinates"`
-}
-
-type Departement struct {
-	Nom  string
-	Code string
-}
-
-type Region struct {
-	Nom  string
-	Code string
-}
 
-type Insee struct {
-	Code string `json:"code"`
-}
+	"github.com/juleur/communes-francaises/api"
+	"github.com/juleur/communes-francaises/cache"
+	"github.com/juleur/communes-francaises/httpclient"
+	"github.com/juleur/communes-francaises/output"
+	"github.com/juleur/communes-francaises/postal"
+	"github.com/juleur/communes-francaises/providers"
+)
 
-type CommuneResponse struct {
-	Type       string `json:"type"`
-	Properties struct {
-		Nom             string   `json:"nom"`
-		Code            string   `json:"code"`
-		CodesPostaux    []string `json:"codesPostaux"`
-		CodeDepartement string   `json:"codeDepartement"`
-		CodeRegion      string   `json:"codeRegion"`
-		Population      int      `json:"population"`
-	} `json:"properties"`
-	Geometry struct {
-		Type        string    `json:"type"`
-		Coordinates []float64 `json:"coordinates"`
-	} `json:"geometry"`
+var formatExtensions = map[string]string{
+	"json":    "json",
+	"geojson": "geojson",
+	"csv":     "csv",
+	"ndjson":  "ndjson",
+	"sqlite":  "db",
 }
 
 func main() {
-	fmt.Println("Recherche des communes............")
-	communeCh, errorCh := communesWorker()
-	communes := readCities(communeCh, errorCh)
-	writeIntoJSON(communes)
-	fmt.Println("Fin de recherche des communes")
-}
-
-func fetchCodeInsee() ([]Insee, error) {
-	resp, err := http.Get("https://geo.api.gouv.fr/communes?fields=nom,code,codesPostaux,codeDepartement,codeRegion,population&format=json&geometry=centre")
-	if err != nil {
-		return []Insee{}, err
-	}
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return []Insee{}, err
+	if len(os.Args) > 1 && os.Args[1] == "api" {
+		runAPI(os.Args[2:])
+		return
 	}
-	insees := []Insee{}
-	err = json.Unmarshal(body, &insees)
-	return insees, err
+	runExport(os.Args[1:])
 }
 
-func fetchDepartements() ([]Departement, error) {
-	resp, err := http.Get("https://geo.api.gouv.fr/departements?fields=nom,code,codeRegion")
-	if err != nil {
-		return []Departement{}, err
-	}
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return []Departement{}, err
-	}
-	departements := []Departement{}
-	err = json.Unmarshal(body, &departements)
-	return departements, err
-}
+// runAPI serves an `api` subcommand: load a previously generated JSON or
+// SQLite export and expose it over HTTP, reusing providers.Commune
+// end-to-end instead of re-running the fetch pipeline.
+func runAPI(args []string) {
+	fs := flag.NewFlagSet("api", flag.ExitOnError)
+	source := fs.String("source", "", "path to a JSON, NDJSON or SQLite export produced by this tool (required)")
+	addr := fs.String("addr", ":8080", "address to listen on")
+	fs.Parse(args)
 
-func fetchRegions() ([]Region, error) {
-	resp, err := http.Get("https://geo.api.gouv.fr/regions?fields=nom,code")
-	if err != nil {
-		return []Region{}, err
+	if *source == "" {
+		log.Fatalln("api: --source is required")
 	}
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
+
+	store, err := api.LoadStore(*source)
 	if err != nil {
-		return []Region{}, err
+		log.Fatalln(err)
 	}
-	regions := []Region{}
-	err = json.Unmarshal(body, &regions)
-	return regions, err
+
+	fmt.Printf("Serving communes from %s on %s\n", *source, *addr)
+	log.Fatalln(http.ListenAndServe(*addr, api.NewRouter(store)))
 }
 
-func communesWorker() (<-chan Commune, <-chan error) {
-	communeCh := make(chan Commune, 10)
-	errorCh := make(chan error, 2)
-	insees, err := fetchCodeInsee()
+func runExport(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	providersFlag := fs.String("providers", "geoapi", "comma-separated list of providers to merge, in priority order (geoapi,geonames)")
+	geonamesCities := fs.String("geonames-cities", "cities15000.txt", "path to the GeoNames cities dump, used by the geonames provider")
+	geonamesAdmin1 := fs.String("geonames-admin1", "admin1CodesASCII.txt", "path to admin1CodesASCII.txt, used by the geonames provider")
+	geonamesCountry := fs.String("geonames-country", "countryInfo.txt", "path to countryInfo.txt, used by the geonames provider")
+	cacheDir := fs.String("cache-dir", ".cache", "directory holding the resumable prefetch cache")
+	resume := fs.Bool("resume", false, "skip codes already present in the cache from a previous run")
+	refresh := fs.String("refresh", "", "re-validate cached entries older than this schedule against upstream (hourly, daily, weekly, monthly, or a Go duration like 72h); empty disables age-based refresh")
+	qps := fs.Float64("qps", 10, "max requests/second against geo.api.gouv.fr")
+	burst := fs.Int("burst", 5, "burst size allowed above --qps")
+	format := fs.String("format", "json", "output format: json, geojson, csv, ndjson or sqlite")
+	outputPath := fs.String("output", "", "output file path; defaults to communesFR_<d>-<m>-<y>.<ext>")
+	fs.Parse(args)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	store, err := cache.NewStore(*cacheDir)
 	if err != nil {
 		log.Fatalln(err)
 	}
-	departements, err := fetchDepartements()
+	manifest, err := cache.LoadManifest(*cacheDir)
 	if err != nil {
 		log.Fatalln(err)
 	}
-	regions, err := fetchRegions()
+	var ttl time.Duration
+	if *refresh != "" {
+		ttl, err = cache.ParseSchedule(*refresh)
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}
+	client := httpclient.New(httpclient.WithQPS(*qps, *burst))
+
+	chain, err := buildProviders(strings.Split(*providersFlag, ","), *geonamesCities, *geonamesAdmin1, *geonamesCountry, client, store, manifest, *resume, ttl)
 	if err != nil {
 		log.Fatalln(err)
 	}
-	go func() {
-		wg := sync.WaitGroup{}
-		for _, insee := range insees {
-			sleeping()
-			wg.Add(1)
-			go geoAPIGouvFr(&wg, insee.Code, departements, regions, communeCh, errorCh)
-		}
-		wg.Wait()
-		close(errorCh)
-		close(communeCh)
-	}()
-	return communeCh, errorCh
-}
 
-func geoAPIGouvFr(wg *sync.WaitGroup, codeInsee string, departements []Departement, regions []Region, communeCh chan<- Commune, errorCh chan<- error) {
-	defer wg.Done()
-	url := fmt.Sprintf("https://geo.api.gouv.fr/communes/%s?fields=nom,code,codesPostaux,codeDepartement,codeRegion,population&format=geojson&geometry=centre", codeInsee)
-	resp, err := http.Get(url)
-	if err != nil || resp.StatusCode == 404 {
-		errorCh <- fmt.Errorf("%s", codeInsee)
-		return
-	}
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		errorCh <- fmt.Errorf("%s", codeInsee)
-		return
+	fmt.Println("Recherche des communes............")
+	merged, errs := providers.Merge(ctx, chain)
+	for _, err := range errs {
+		logErrors(err)
 	}
-	cs := CommuneResponse{}
-	if err = json.Unmarshal(body, &cs); err != nil {
-		errorCh <- fmt.Errorf("%s", codeInsee)
-		return
+	if err := manifest.Flush(); err != nil {
+		logErrors(err)
 	}
-	c := Commune{}
-	c.Nom = cs.Properties.Nom
-	if cs.Properties.CodeDepartement != "" {
-		for _, dep := range departements {
-			if dep.Code == cs.Properties.CodeDepartement {
-				c.Departement = dep.Nom
-			}
-		}
+	communes := toSortedSlice(merged)
+	if err := writeOutput(*format, *outputPath, communes); err != nil {
+		log.Fatalln(err)
 	}
-	if cs.Properties.CodeRegion != "" {
-		for _, reg := range regions {
-			if reg.Code == cs.Properties.CodeRegion {
-				c.Region = reg.Nom
-			}
+	fmt.Println("Fin de recherche des communes")
+}
+
+// buildProviders turns the --providers flag into the ordered provider chain
+// Merge expects. Unknown names are a fatal configuration error rather than a
+// silent no-op, and so is a chain made up only of name-only providers
+// (geonames today): with no INSEE-keyed provider to join against, every one
+// of their records would be silently dropped by Merge.
+func buildProviders(names []string, citiesPath, admin1Path, countryPath string, client *httpclient.Client, store *cache.Store, manifest *cache.Manifest, resume bool, ttl time.Duration) ([]providers.Provider, error) {
+	chain := make([]providers.Provider, 0, len(names))
+	haveInseeKeyed := false
+	haveNameOnly := false
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		switch name {
+		case "geoapi":
+			chain = append(chain, &providers.GeoAPIGouvFr{
+				HTTPClient: client,
+				LogErrors:  logErrors,
+				Cache:      store,
+				Manifest:   manifest,
+				Resume:     resume,
+				TTL:        ttl,
+			})
+			haveInseeKeyed = true
+		case "geonames":
+			chain = append(chain, &providers.GeoNames{
+				CitiesPath:  citiesPath,
+				Admin1Path:  admin1Path,
+				CountryPath: countryPath,
+			})
+			haveNameOnly = true
+		default:
+			return nil, fmt.Errorf("unknown provider %q", name)
 		}
 	}
-	if len(cs.Properties.CodesPostaux) > 0 {
-		c.CodesPostaux = cs.Properties.CodesPostaux
-	}
-	if len(cs.Geometry.Coordinates) > 0 {
-		c.Location.Type = "Point"
-		c.Location.Coordinates = append(c.Location.Coordinates, cs.Geometry.Coordinates[1])
-		c.Location.Coordinates = append(c.Location.Coordinates, cs.Geometry.Coordinates[0])
+	if haveNameOnly && !haveInseeKeyed {
+		return nil, fmt.Errorf("--providers %q: geonames has no INSEE code of its own and joins by name against an INSEE-keyed provider (geoapi) - include geoapi in the chain", strings.Join(names, ","))
 	}
-	communeCh <- c
+	return chain, nil
 }
 
-func readCities(communeCh <-chan Commune, errorCh <-chan error) []Commune {
-	ticker := time.NewTicker(15 * time.Minute)
-	var communes []Commune
-	var communeCounter int
-	for communeCh != nil || errorCh != nil {
-		select {
-		case commune, ok := <-communeCh:
-			if !ok {
-				communeCh = nil
-				continue
-			}
-			communes = append(communes, commune)
-			communeCounter += 1
-		case err, ok := <-errorCh:
-			if !ok {
-				errorCh = nil
-				continue
-			}
-			logErrors(err)
-		case <-ticker.C:
-			t := time.Now().UTC()
-			fmt.Printf("[%s] %d communes ont été traitées\n", t.Format("02-01-2006 15:04:05"), communeCounter)
-		}
+func toSortedSlice(communes map[string]providers.Commune) []providers.Commune {
+	slice := make([]providers.Commune, 0, len(communes))
+	for _, c := range communes {
+		slice = append(slice, c)
 	}
-	ticker.Stop()
-
-	sort.SliceStable(communes, func(i, j int) bool {
-		if len(communes[i].CodesPostaux) == 0 || len(communes[j].CodesPostaux) == 0 {
-			return true
-		}
-		o, err := strconv.ParseInt(strings.TrimLeft(communes[i].CodesPostaux[0], "0"), 10, 64)
-		if err != nil {
-			return true
-		}
-		k, err := strconv.ParseInt(strings.TrimLeft(communes[j].CodesPostaux[0], "0"), 10, 64)
-		if err != nil {
-			return true
-		}
-		return o < k
+	sort.SliceStable(slice, func(i, j int) bool {
+		return sortKey(slice[i]) < sortKey(slice[j])
 	})
-	return communes
+	return slice
 }
 
-func writeIntoJSON(communes []Commune) {
-	jsonData, err := json.MarshalIndent(communes, "", " ")
+// sortKey orders communes by department code, then postal code, then name,
+// so overseas codes sort correctly alongside metropolitan ones and communes
+// split across several postal codes (Paris arrondissements, Marseille) land
+// next to each other instead of wherever ParseInt happened to fail.
+func sortKey(c providers.Commune) string {
+	codePostal := lowestCodePostal(c.CodesPostaux)
+	dep, err := postal.DepartmentCode(codePostal)
 	if err != nil {
-		log.Fatalln(err)
+		dep = "ZZ"
 	}
-	filename := fmt.Sprintf("communesFR_%d-%d-%d.json", time.Now().Day(), int(time.Now().Month()), time.Now().Year())
-	err = ioutil.WriteFile(filename, jsonData, 0644)
-	if err != nil {
-		log.Fatalln(err)
+	return fmt.Sprintf("%s|%s|%s", dep, codePostal, c.Nom)
+}
+
+// lowestCodePostal picks the smallest postal code for communes that have
+// several, so the pick is deterministic rather than relying on provider
+// ordering.
+func lowestCodePostal(codes []string) string {
+	if len(codes) == 0 {
+		return ""
 	}
+	lowest := codes[0]
+	for _, code := range codes[1:] {
+		if code < lowest {
+			lowest = code
+		}
+	}
+	return lowest
 }
 
-func sleeping() {
-	rand.Seed(time.Now().UnixNano())
-	sleeper := rand.Intn(75-35) + 35
-	time.Sleep(time.Duration(sleeper) * time.Millisecond)
+// writeOutput streams communes into the encoder for format rather than
+// marshaling the whole slice at once.
+func writeOutput(format, path string, communes []providers.Commune) error {
+	ext, ok := formatExtensions[format]
+	if !ok {
+		return fmt.Errorf("unknown format %q", format)
+	}
+	if path == "" {
+		path = fmt.Sprintf("communesFR_%d-%d-%d.%s", time.Now().Day(), int(time.Now().Month()), time.Now().Year(), ext)
+	}
+
+	enc, err := output.New(format, path)
+	if err != nil {
+		return err
+	}
+	for _, c := range communes {
+		if err := enc.Write(c); err != nil {
+			enc.Close()
+			return err
+		}
+	}
+	return enc.Close()
 }
 
 func logErrors(er error) {
@@ -0,0 +1,77 @@
+package output
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+
+	"github.com/juleur/communes-francaises/providers"
+)
+
+var csvHeader = []string{
+	"codeInsee", "codePostal", "nom", "departement", "region",
+	"population", "elevation", "timezone", "latitude", "longitude",
+}
+
+// csvEncoder writes one row per (commune, code postal): a commune with
+// several postal codes (Paris arrondissements, Marseille) gets one row per
+// code rather than a single row with a packed list column.
+type csvEncoder struct {
+	w   io.Closer
+	csv *csv.Writer
+}
+
+func newCSVEncoder(w io.WriteCloser) (*csvEncoder, error) {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return nil, err
+	}
+	return &csvEncoder{w: w, csv: cw}, nil
+}
+
+func (e *csvEncoder) Write(c providers.Commune) error {
+	lat, lon := "", ""
+	if len(c.Location.Coordinates) == 2 {
+		lat = strconv.FormatFloat(c.Location.Coordinates[0], 'f', -1, 64)
+		lon = strconv.FormatFloat(c.Location.Coordinates[1], 'f', -1, 64)
+	}
+
+	codesPostaux := c.CodesPostaux
+	if len(codesPostaux) == 0 {
+		codesPostaux = []string{""}
+	}
+	for _, codePostal := range codesPostaux {
+		row := []string{
+			c.CodeInsee,
+			codePostal,
+			c.Nom,
+			c.Departement,
+			c.Region,
+			intOrEmpty(c.Population),
+			intOrEmpty(c.Elevation),
+			c.Timezone,
+			lat,
+			lon,
+		}
+		if err := e.csv.Write(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func intOrEmpty(v int) string {
+	if v == 0 {
+		return ""
+	}
+	return strconv.Itoa(v)
+}
+
+func (e *csvEncoder) Close() error {
+	e.csv.Flush()
+	if err := e.csv.Error(); err != nil {
+		e.w.Close()
+		return err
+	}
+	return e.w.Close()
+}
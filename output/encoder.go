@@ -0,0 +1,64 @@
+// Package output streams Commune records to the exporter's supported file
+// formats. Encoders are written one commune at a time so the pipeline never
+// has to hold the full ~35k-commune dataset in memory before marshaling.
+package output
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/juleur/communes-francaises/providers"
+)
+
+// Encoder writes a stream of communes to an output format.
+type Encoder interface {
+	// Write encodes a single commune. Implementations that need
+	// header/footer framing (GeoJSON's FeatureCollection wrapper, for
+	// instance) write it lazily around the stream of Write calls.
+	Write(c providers.Commune) error
+	// Close flushes any buffered framing and releases the underlying
+	// file or database handle. It must be called exactly once, after the
+	// last Write.
+	Close() error
+}
+
+// New opens path and returns the Encoder for format. Supported formats are
+// "json" (the exporter's original single-array output), "geojson", "csv",
+// "ndjson" and "sqlite".
+func New(format, path string) (Encoder, error) {
+	switch format {
+	case "json":
+		f, err := os.Create(path)
+		if err != nil {
+			return nil, err
+		}
+		return newJSONEncoder(f), nil
+	case "geojson":
+		f, err := os.Create(path)
+		if err != nil {
+			return nil, err
+		}
+		return newGeoJSONEncoder(f), nil
+	case "csv":
+		f, err := os.Create(path)
+		if err != nil {
+			return nil, err
+		}
+		enc, err := newCSVEncoder(f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		return enc, nil
+	case "ndjson":
+		f, err := os.Create(path)
+		if err != nil {
+			return nil, err
+		}
+		return newNDJSONEncoder(f), nil
+	case "sqlite":
+		return newSQLiteEncoder(path)
+	default:
+		return nil, fmt.Errorf("output: unknown format %q", format)
+	}
+}
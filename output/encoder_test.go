@@ -0,0 +1,206 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/juleur/communes-francaises/providers"
+)
+
+// fakeWriteCloser fails its (failAfter+1)th Write call and records whether
+// Close was called, so encoder tests can assert Close still runs (and still
+// releases the underlying handle) after a failed Write.
+type fakeWriteCloser struct {
+	bytes.Buffer
+	failAfter int
+	writes    int
+	closed    bool
+}
+
+func (f *fakeWriteCloser) Write(p []byte) (int, error) {
+	f.writes++
+	if f.writes > f.failAfter {
+		return 0, errors.New("write failed")
+	}
+	return f.Buffer.Write(p)
+}
+
+func (f *fakeWriteCloser) Close() error {
+	f.closed = true
+	return nil
+}
+
+func paris() providers.Commune {
+	return providers.Commune{
+		CodeInsee:   "75056",
+		Nom:         "Paris",
+		Departement: "Paris",
+	}
+}
+
+func TestJSONEncoderClosesAfterFailedWrite(t *testing.T) {
+	w := &fakeWriteCloser{failAfter: 0}
+	enc := newJSONEncoder(w)
+
+	if err := enc.Write(paris()); err == nil {
+		t.Fatal("expected Write to fail")
+	}
+	if err := enc.Close(); err == nil {
+		t.Fatal("expected Close to return the Write error")
+	}
+	if !w.closed {
+		t.Error("Close should release the underlying writer even after a failed Write")
+	}
+}
+
+func TestJSONEncoderRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	w := &fakeWriteCloser{failAfter: 1 << 20}
+	enc := newJSONEncoder(w)
+	if err := enc.Write(paris()); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	buf = w.Buffer
+
+	var got []providers.Commune
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, buf.String())
+	}
+	if len(got) != 1 || got[0].CodeInsee != "75056" {
+		t.Errorf("got %+v, want a single Paris record", got)
+	}
+}
+
+func TestJSONEncoderEmptyArray(t *testing.T) {
+	w := &fakeWriteCloser{failAfter: 1 << 20}
+	enc := newJSONEncoder(w)
+	if err := enc.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if w.Buffer.String() != "[]" {
+		t.Errorf("Close() with no writes = %q, want %q", w.Buffer.String(), "[]")
+	}
+}
+
+func TestGeoJSONEncoderClosesAfterFailedWrite(t *testing.T) {
+	w := &fakeWriteCloser{failAfter: 0}
+	enc := newGeoJSONEncoder(w)
+
+	if err := enc.Write(paris()); err == nil {
+		t.Fatal("expected Write to fail")
+	}
+	if err := enc.Close(); err == nil {
+		t.Fatal("expected Close to return the Write error")
+	}
+	if !w.closed {
+		t.Error("Close should release the underlying writer even after a failed Write")
+	}
+}
+
+func TestGeoJSONEncoderRoundTrip(t *testing.T) {
+	w := &fakeWriteCloser{failAfter: 1 << 20}
+	enc := newGeoJSONEncoder(w)
+	if err := enc.Write(paris()); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	var got struct {
+		Type     string `json:"type"`
+		Features []struct {
+			Properties struct {
+				CodeInsee string `json:"codeInsee"`
+			} `json:"properties"`
+		} `json:"features"`
+	}
+	if err := json.Unmarshal(w.Buffer.Bytes(), &got); err != nil {
+		t.Fatalf("output is not valid GeoJSON: %v\n%s", err, w.Buffer.String())
+	}
+	if got.Type != "FeatureCollection" || len(got.Features) != 1 || got.Features[0].Properties.CodeInsee != "75056" {
+		t.Errorf("got %+v, want a single FeatureCollection with Paris", got)
+	}
+}
+
+func TestCSVEncoderRoundTrip(t *testing.T) {
+	w := &fakeWriteCloser{failAfter: 1 << 20}
+	enc, err := newCSVEncoder(w)
+	if err != nil {
+		t.Fatalf("newCSVEncoder: %v", err)
+	}
+	c := paris()
+	c.CodesPostaux = []string{"75001", "75002"}
+	if err := enc.Write(c); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if !w.closed {
+		t.Error("Close should release the underlying writer")
+	}
+
+	lines := strings.Split(strings.TrimRight(w.Buffer.String(), "\n"), "\n")
+	if len(lines) != 3 { // header + one row per code postal
+		t.Fatalf("got %d lines, want 3 (header + 2 rows):\n%s", len(lines), w.Buffer.String())
+	}
+}
+
+func TestNDJSONEncoderRoundTrip(t *testing.T) {
+	w := &fakeWriteCloser{failAfter: 1 << 20}
+	enc := newNDJSONEncoder(w)
+	if err := enc.Write(paris()); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := enc.Write(providers.Commune{CodeInsee: "13055", Nom: "Marseille"}); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if !w.closed {
+		t.Error("Close should release the underlying writer")
+	}
+
+	lines := strings.Split(strings.TrimRight(w.Buffer.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2 (one JSON object per commune):\n%s", len(lines), w.Buffer.String())
+	}
+	for _, line := range lines {
+		var c providers.Commune
+		if err := json.Unmarshal([]byte(line), &c); err != nil {
+			t.Errorf("line is not valid JSON: %v: %s", err, line)
+		}
+	}
+}
+
+func TestNewUnknownFormat(t *testing.T) {
+	if _, err := New("xml", t.TempDir()+"/out.xml"); err == nil {
+		t.Error("New with an unknown format should return an error")
+	}
+}
+
+func TestNewWritesAndClosesEachFormat(t *testing.T) {
+	for _, format := range []string{"json", "geojson", "csv", "ndjson"} {
+		t.Run(format, func(t *testing.T) {
+			path := t.TempDir() + "/out." + format
+			enc, err := New(format, path)
+			if err != nil {
+				t.Fatalf("New(%q): %v", format, err)
+			}
+			if err := enc.Write(paris()); err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+			if err := enc.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package output
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/juleur/communes-francaises/providers"
+)
+
+// feature is one commune as an RFC 7946 GeoJSON Feature.
+type feature struct {
+	Type       string `json:"type"`
+	Properties struct {
+		Nom          string   `json:"nom"`
+		CodeInsee    string   `json:"codeInsee"`
+		Departement  string   `json:"departement,omitempty"`
+		Region       string   `json:"region,omitempty"`
+		CodesPostaux []string `json:"codesPostaux"`
+		Population   int      `json:"population,omitempty"`
+		Elevation    int      `json:"elevation,omitempty"`
+		Timezone     string   `json:"timezone,omitempty"`
+	} `json:"properties"`
+	Geometry providers.Location `json:"geometry"`
+}
+
+// geoJSONEncoder streams communes as a GeoJSON FeatureCollection, so the
+// file is directly loadable in QGIS/Leaflet without buffering every
+// commune before marshaling.
+type geoJSONEncoder struct {
+	w      io.WriteCloser
+	wrote  bool
+	failed error
+}
+
+func newGeoJSONEncoder(w io.WriteCloser) *geoJSONEncoder {
+	return &geoJSONEncoder{w: w}
+}
+
+func (e *geoJSONEncoder) Write(c providers.Commune) error {
+	if e.failed != nil {
+		return e.failed
+	}
+	if !e.wrote {
+		if _, err := io.WriteString(e.w, `{"type":"FeatureCollection","features":[`); err != nil {
+			return e.fail(err)
+		}
+	} else {
+		if _, err := io.WriteString(e.w, ","); err != nil {
+			return e.fail(err)
+		}
+	}
+	e.wrote = true
+
+	f := feature{Type: "Feature"}
+	f.Properties.Nom = c.Nom
+	f.Properties.CodeInsee = c.CodeInsee
+	f.Properties.Departement = c.Departement
+	f.Properties.Region = c.Region
+	f.Properties.CodesPostaux = c.CodesPostaux
+	f.Properties.Population = c.Population
+	f.Properties.Elevation = c.Elevation
+	f.Properties.Timezone = c.Timezone
+	f.Geometry = c.Location
+
+	data, err := json.Marshal(f)
+	if err != nil {
+		return e.fail(err)
+	}
+	if _, err := e.w.Write(data); err != nil {
+		return e.fail(err)
+	}
+	return nil
+}
+
+func (e *geoJSONEncoder) fail(err error) error {
+	e.failed = err
+	return err
+}
+
+func (e *geoJSONEncoder) Close() error {
+	if e.failed != nil {
+		e.w.Close()
+		return e.failed
+	}
+	if !e.wrote {
+		if _, err := io.WriteString(e.w, `{"type":"FeatureCollection","features":[`); err != nil {
+			e.w.Close()
+			return err
+		}
+	}
+	if _, err := io.WriteString(e.w, "]}"); err != nil {
+		e.w.Close()
+		return err
+	}
+	return e.w.Close()
+}
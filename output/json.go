@@ -0,0 +1,67 @@
+package output
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/juleur/communes-francaises/providers"
+)
+
+// jsonEncoder reproduces the exporter's original output: a single JSON
+// array of communes, indented like json.MarshalIndent used to produce, but
+// written incrementally instead of marshaling the whole slice at once.
+type jsonEncoder struct {
+	w      io.WriteCloser
+	wrote  bool
+	failed error
+}
+
+func newJSONEncoder(w io.WriteCloser) *jsonEncoder {
+	return &jsonEncoder{w: w}
+}
+
+func (e *jsonEncoder) Write(c providers.Commune) error {
+	if e.failed != nil {
+		return e.failed
+	}
+	prefix := ",\n "
+	if !e.wrote {
+		prefix = "[\n "
+	}
+	e.wrote = true
+	if _, err := io.WriteString(e.w, prefix); err != nil {
+		return e.fail(err)
+	}
+	data, err := json.MarshalIndent(c, " ", " ")
+	if err != nil {
+		return e.fail(err)
+	}
+	if _, err := e.w.Write(data); err != nil {
+		return e.fail(err)
+	}
+	return nil
+}
+
+func (e *jsonEncoder) fail(err error) error {
+	e.failed = err
+	return err
+}
+
+func (e *jsonEncoder) Close() error {
+	if e.failed != nil {
+		e.w.Close()
+		return e.failed
+	}
+	if !e.wrote {
+		if _, err := io.WriteString(e.w, "[]"); err != nil {
+			e.w.Close()
+			return err
+		}
+		return e.w.Close()
+	}
+	if _, err := io.WriteString(e.w, "\n]"); err != nil {
+		e.w.Close()
+		return err
+	}
+	return e.w.Close()
+}
@@ -0,0 +1,27 @@
+package output
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/juleur/communes-francaises/providers"
+)
+
+// ndjsonEncoder writes one JSON object per line, for streaming ingest by
+// tools that don't want to hold (or wait for) a whole JSON array.
+type ndjsonEncoder struct {
+	w   io.WriteCloser
+	enc *json.Encoder
+}
+
+func newNDJSONEncoder(w io.WriteCloser) *ndjsonEncoder {
+	return &ndjsonEncoder{w: w, enc: json.NewEncoder(w)}
+}
+
+func (e *ndjsonEncoder) Write(c providers.Commune) error {
+	return e.enc.Encode(c)
+}
+
+func (e *ndjsonEncoder) Close() error {
+	return e.w.Close()
+}
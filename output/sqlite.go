@@ -0,0 +1,144 @@
+package output
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/juleur/communes-francaises/providers"
+)
+
+const sqliteSchema = `
+CREATE TABLE communes (
+	id           INTEGER PRIMARY KEY,
+	code_insee   TEXT NOT NULL UNIQUE,
+	nom          TEXT NOT NULL,
+	departement  TEXT,
+	code_departement TEXT,
+	region       TEXT,
+	population   INTEGER,
+	elevation    INTEGER,
+	timezone     TEXT,
+	lat          REAL,
+	lon          REAL,
+	alternate_names_json TEXT
+);
+
+CREATE TABLE codes_postaux (
+	commune_id  INTEGER NOT NULL REFERENCES communes(id),
+	code_postal TEXT NOT NULL
+);
+CREATE INDEX idx_codes_postaux_code ON codes_postaux(code_postal);
+
+-- R-Tree spatial index so "/communes/near" radius queries can prune to a
+-- bounding box before the exact Haversine check, instead of scanning every
+-- commune.
+CREATE VIRTUAL TABLE communes_rtree USING rtree(
+	id,
+	min_lat, max_lat,
+	min_lon, max_lon
+);
+`
+
+// sqliteEncoder writes communes to a SQLite database with an R-Tree
+// spatial index on lat/lon, for radius queries served by the api package.
+type sqliteEncoder struct {
+	db         *sql.DB
+	insert     *sql.Stmt
+	insertCP   *sql.Stmt
+	insertTree *sql.Stmt
+	tx         *sql.Tx
+	count      int64
+}
+
+func newSQLiteEncoder(path string) (*sqliteEncoder, error) {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	insert, err := tx.Prepare(`INSERT INTO communes
+		(code_insee, nom, departement, code_departement, region, population, elevation, timezone, lat, lon, alternate_names_json)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		tx.Rollback()
+		db.Close()
+		return nil, err
+	}
+	insertCP, err := tx.Prepare(`INSERT INTO codes_postaux (commune_id, code_postal) VALUES (?, ?)`)
+	if err != nil {
+		tx.Rollback()
+		db.Close()
+		return nil, err
+	}
+	insertTree, err := tx.Prepare(`INSERT INTO communes_rtree (id, min_lat, max_lat, min_lon, max_lon) VALUES (?, ?, ?, ?, ?)`)
+	if err != nil {
+		tx.Rollback()
+		db.Close()
+		return nil, err
+	}
+
+	return &sqliteEncoder{db: db, tx: tx, insert: insert, insertCP: insertCP, insertTree: insertTree}, nil
+}
+
+func (e *sqliteEncoder) Write(c providers.Commune) error {
+	var lat, lon sql.NullFloat64
+	if len(c.Location.Coordinates) == 2 {
+		lat = sql.NullFloat64{Float64: c.Location.Coordinates[0], Valid: true}
+		lon = sql.NullFloat64{Float64: c.Location.Coordinates[1], Valid: true}
+	}
+	altNames, err := json.Marshal(c.AlternateNames)
+	if err != nil {
+		return err
+	}
+
+	result, err := e.insert.Exec(c.CodeInsee, c.Nom, c.Departement, c.CodeDepartement, c.Region, c.Population, c.Elevation, c.Timezone, lat, lon, string(altNames))
+	if err != nil {
+		return fmt.Errorf("output: insert commune %s: %w", c.CodeInsee, err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	for _, codePostal := range c.CodesPostaux {
+		if _, err := e.insertCP.Exec(id, codePostal); err != nil {
+			return err
+		}
+	}
+
+	if lat.Valid && lon.Valid {
+		if _, err := e.insertTree.Exec(id, lat.Float64, lat.Float64, lon.Float64, lon.Float64); err != nil {
+			return err
+		}
+	}
+
+	e.count++
+	return nil
+}
+
+func (e *sqliteEncoder) Close() error {
+	e.insert.Close()
+	e.insertCP.Close()
+	e.insertTree.Close()
+	if err := e.tx.Commit(); err != nil {
+		e.db.Close()
+		return err
+	}
+	return e.db.Close()
+}
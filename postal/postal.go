@@ -0,0 +1,103 @@
+// Package postal validates and normalizes French postal codes, including
+// the overseas collectivities whose codes don't follow the metropolitan
+// 01xxx-95xxx range, following the per-territory address metadata approach
+// used by projects like Boostport/address.
+package postal
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Territory is a French department or overseas collectivity identified by
+// its postal code prefix.
+type Territory struct {
+	Prefix string
+	Name   string
+}
+
+// territories lists every postal code prefix this package recognizes,
+// ordered longest-prefix-first so overseas codes (3-digit prefix) are
+// matched before the broader metropolitan range.
+var territories = []Territory{
+	{"971", "Guadeloupe"},
+	{"972", "Martinique"},
+	{"973", "Guyane"},
+	{"974", "La Réunion"},
+	{"975", "Saint-Pierre-et-Miquelon"},
+	{"976", "Mayotte"},
+	{"977", "Saint-Barthélemy"},
+	{"978", "Saint-Martin"},
+	{"980", "Monaco"},
+	{"984", "Terres australes et antarctiques françaises"},
+	{"986", "Wallis-et-Futuna"},
+	{"987", "Polynésie française"},
+	{"988", "Nouvelle-Calédonie"},
+}
+
+var (
+	// metropolitanPattern covers 01000-95999, the mainland French
+	// department range (Corsica's 20xxx included).
+	metropolitanPattern = regexp.MustCompile(`^(?:0[1-9]|[1-8]\d|9[0-5])\d{3}$`)
+	// overseasPattern covers the 3-digit-prefix territories in
+	// territories above.
+	overseasPattern = regexp.MustCompile(`^(?:971|972|973|974|975|976|977|978|980|984|986|987|988)\d{2}$`)
+)
+
+// Normalize trims whitespace and left-pads short metropolitan codes (e.g.
+// "1000" for L'Abergement-Clémenciat, in Ain, becomes "01000") to their
+// canonical 5-digit form, without altering already-valid codes.
+func Normalize(codePostal string) (string, error) {
+	code := strings.TrimSpace(codePostal)
+	if len(code) < 5 {
+		n, err := strconv.Atoi(code)
+		if err != nil {
+			return "", fmt.Errorf("postal: %q is not a numeric postal code", codePostal)
+		}
+		code = fmt.Sprintf("%05d", n)
+	}
+	if err := Validate(code); err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+// Validate reports whether codePostal is a well-formed French postal code,
+// metropolitan or overseas.
+func Validate(codePostal string) error {
+	if !metropolitanPattern.MatchString(codePostal) && !overseasPattern.MatchString(codePostal) {
+		return fmt.Errorf("postal: %q is not a valid French postal code", codePostal)
+	}
+	return nil
+}
+
+// DepartmentCode returns the department or territory code a postal code
+// belongs to: the first two digits for metropolitan codes (with Corsica's
+// historical 20 prefix left as-is; this package does not disambiguate 2A
+// from 2B since the postal code alone can't), or the matching 3-digit
+// overseas prefix.
+func DepartmentCode(codePostal string) (string, error) {
+	if err := Validate(codePostal); err != nil {
+		return "", err
+	}
+	for _, t := range territories {
+		if strings.HasPrefix(codePostal, t.Prefix) {
+			return t.Prefix, nil
+		}
+	}
+	return codePostal[:2], nil
+}
+
+// TerritoryName returns the human-readable department/territory name for
+// an overseas postal code, or "" for metropolitan codes (which this
+// package does not map to a department name, only a code).
+func TerritoryName(codePostal string) string {
+	for _, t := range territories {
+		if strings.HasPrefix(codePostal, t.Prefix) {
+			return t.Name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,83 @@
+package postal
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	cases := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: " 1000 ", want: "01000"},
+		{in: "75001", want: "75001"},
+		{in: "97400", want: "97400"},
+		{in: "abcde", wantErr: true},
+		{in: "999999", wantErr: true},
+	}
+	for _, tc := range cases {
+		got, err := Normalize(tc.in)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("Normalize(%q): expected error, got %q", tc.in, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Normalize(%q): unexpected error: %v", tc.in, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := []string{"01000", "75001", "20000", "95999", "97400", "98800"}
+	for _, code := range valid {
+		if err := Validate(code); err != nil {
+			t.Errorf("Validate(%q): unexpected error: %v", code, err)
+		}
+	}
+
+	invalid := []string{"", "00000", "96000", "97900", "1234", "123456", "abcde"}
+	for _, code := range invalid {
+		if err := Validate(code); err == nil {
+			t.Errorf("Validate(%q): expected error, got nil", code)
+		}
+	}
+}
+
+func TestDepartmentCode(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{"75001", "75"},
+		{"20000", "20"},
+		{"97400", "974"},
+		{"98800", "988"},
+	}
+	for _, tc := range cases {
+		got, err := DepartmentCode(tc.in)
+		if err != nil {
+			t.Fatalf("DepartmentCode(%q): unexpected error: %v", tc.in, err)
+		}
+		if got != tc.want {
+			t.Errorf("DepartmentCode(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+
+	if _, err := DepartmentCode("not-a-code"); err == nil {
+		t.Error("DepartmentCode on an invalid code: expected error, got nil")
+	}
+}
+
+func TestTerritoryName(t *testing.T) {
+	if got := TerritoryName("97400"); got != "La Réunion" {
+		t.Errorf("TerritoryName(97400) = %q, want %q", got, "La Réunion")
+	}
+	if got := TerritoryName("75001"); got != "" {
+		t.Errorf("TerritoryName(75001) = %q, want empty", got)
+	}
+}
@@ -0,0 +1,329 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/juleur/communes-francaises/cache"
+	"github.com/juleur/communes-francaises/httpclient"
+)
+
+// GeoAPIGouvFr fetches communes from the official geo.api.gouv.fr REST API.
+// It is the historical, authoritative source for nom/departement/region and
+// is the default first provider in --providers.
+type GeoAPIGouvFr struct {
+	// HTTPClient performs every request. Required: geoAPIGouvFr has no
+	// fallback to the bare net/http client, so rate limiting, retries and
+	// the circuit breaker are always in effect.
+	HTTPClient *httpclient.Client
+
+	// LogErrors receives one error per commune whose failure is permanent
+	// (see httpclient.IsPermanent); transient failures are left for the
+	// retry loop and --resume instead of being logged. If nil, permanent
+	// errors are silently dropped.
+	LogErrors func(error)
+
+	// Cache, when set, persists each response and lets subsequent runs
+	// skip or conditionally revalidate communes already on disk.
+	Cache *cache.Store
+	// Manifest tracks which codes have completed and which failed, so
+	// --resume can skip the former and a retry pass can target the
+	// latter. Required when Cache is set.
+	Manifest *cache.Manifest
+	// Resume skips codes the Manifest already marked done, without even
+	// a conditional request, unless TTL says the entry is stale.
+	Resume bool
+	// TTL is --refresh turned into a duration: a cached entry younger
+	// than TTL is served as-is, with no request at all, independently of
+	// Resume. Once it is older than TTL (or TTL is zero, meaning
+	// --refresh was not given), fetchCommune falls through to a
+	// conditional GET that revalidates it by ETag/Last-Modified.
+	TTL time.Duration
+}
+
+type insee struct {
+	Code string `json:"code"`
+}
+
+type departement struct {
+	Nom  string
+	Code string
+}
+
+type region struct {
+	Nom  string
+	Code string
+}
+
+type communeResponse struct {
+	Type       string `json:"type"`
+	Properties struct {
+		Nom             string   `json:"nom"`
+		Code            string   `json:"code"`
+		CodesPostaux    []string `json:"codesPostaux"`
+		CodeDepartement string   `json:"codeDepartement"`
+		CodeRegion      string   `json:"codeRegion"`
+		Population      int      `json:"population"`
+	} `json:"properties"`
+	Geometry struct {
+		Type        string    `json:"type"`
+		Coordinates []float64 `json:"coordinates"`
+	} `json:"geometry"`
+}
+
+func (g *GeoAPIGouvFr) Name() string {
+	return "geoapi"
+}
+
+func (g *GeoAPIGouvFr) Fetch(ctx context.Context) (map[string]Commune, error) {
+	insees, err := g.fetchCodeInsee(ctx)
+	if err != nil {
+		return nil, err
+	}
+	departements, err := g.fetchDepartements(ctx)
+	if err != nil {
+		return nil, err
+	}
+	regions, err := g.fetchRegions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	communeCh := make(chan Commune, 10)
+	errorCh := make(chan error, 2)
+	go func() {
+		wg := sync.WaitGroup{}
+	insees:
+		for _, ins := range insees {
+			select {
+			case <-ctx.Done():
+				break insees
+			default:
+			}
+			wg.Add(1)
+			go g.fetchCommune(ctx, &wg, ins.Code, departements, regions, communeCh, errorCh)
+		}
+		wg.Wait()
+		close(errorCh)
+		close(communeCh)
+	}()
+
+	communes := make(map[string]Commune, len(insees))
+	for communeCh != nil || errorCh != nil {
+		select {
+		case c, ok := <-communeCh:
+			if !ok {
+				communeCh = nil
+				continue
+			}
+			communes[c.CodeInsee] = c
+		case err, ok := <-errorCh:
+			if !ok {
+				errorCh = nil
+				continue
+			}
+			if g.LogErrors != nil {
+				g.LogErrors(err)
+			}
+		}
+	}
+	if ctx.Err() != nil {
+		return communes, ctx.Err()
+	}
+	return communes, nil
+}
+
+func (g *GeoAPIGouvFr) fetchCodeInsee(ctx context.Context) ([]insee, error) {
+	insees := []insee{}
+	body, err := g.get(ctx, "https://geo.api.gouv.fr/communes?fields=nom,code,codesPostaux,codeDepartement,codeRegion,population&format=json&geometry=centre")
+	if err != nil {
+		return insees, err
+	}
+	err = json.Unmarshal(body, &insees)
+	return insees, err
+}
+
+func (g *GeoAPIGouvFr) fetchDepartements(ctx context.Context) ([]departement, error) {
+	departements := []departement{}
+	body, err := g.get(ctx, "https://geo.api.gouv.fr/departements?fields=nom,code,codeRegion")
+	if err != nil {
+		return departements, err
+	}
+	err = json.Unmarshal(body, &departements)
+	return departements, err
+}
+
+func (g *GeoAPIGouvFr) fetchRegions(ctx context.Context) ([]region, error) {
+	regions := []region{}
+	body, err := g.get(ctx, "https://geo.api.gouv.fr/regions?fields=nom,code")
+	if err != nil {
+		return regions, err
+	}
+	err = json.Unmarshal(body, &regions)
+	return regions, err
+}
+
+// get performs a rate-limited, retrying GET and returns the response body.
+func (g *GeoAPIGouvFr) get(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := g.HTTPClient.Do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return ioutil.ReadAll(resp.Body)
+}
+
+func (g *GeoAPIGouvFr) fetchCommune(ctx context.Context, wg *sync.WaitGroup, codeInsee string, departements []departement, regions []region, communeCh chan<- Commune, errorCh chan<- error) {
+	defer wg.Done()
+
+	var cached cache.Entry
+	var haveCached bool
+	if g.Cache != nil {
+		cached, haveCached = mustLoad(g.Cache, codeInsee)
+		fresh := haveCached && !cached.Stale(g.TTL)
+		resumeSkip := g.Resume && g.Manifest != nil && g.Manifest.IsDone(codeInsee) && fresh
+		// --refresh alone (no --resume) skips the request entirely for
+		// any entry younger than its TTL; only once it ages past TTL
+		// does the conditional GET below run to revalidate it.
+		refreshSkip := g.TTL > 0 && fresh
+		if resumeSkip || refreshSkip {
+			if c, err := decodeCommune(cached); err == nil {
+				communeCh <- c
+				return
+			}
+		}
+	}
+
+	url := fmt.Sprintf("https://geo.api.gouv.fr/communes/%s?fields=nom,code,codesPostaux,codeDepartement,codeRegion,population&format=geojson&geometry=centre", codeInsee)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		g.fail(codeInsee, err, errorCh)
+		return
+	}
+	if haveCached {
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	resp, err := g.HTTPClient.Do(ctx, req)
+	if err != nil {
+		// A 404 (surfaced as httpclient.PermanentError) just means this
+		// code INSEE no longer exists upstream; log it like any other
+		// permanent failure instead of special-casing it.
+		g.fail(codeInsee, err, errorCh)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && haveCached {
+		cached.FetchedAt = time.Now()
+		g.save(codeInsee, cached)
+		if c, err := decodeCommune(cached); err == nil {
+			communeCh <- c
+			return
+		}
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		g.fail(codeInsee, err, errorCh)
+		return
+	}
+	cs := communeResponse{}
+	if err = json.Unmarshal(body, &cs); err != nil {
+		g.fail(codeInsee, err, errorCh)
+		return
+	}
+	c := Commune{CodeInsee: codeInsee}
+	c.Nom = cs.Properties.Nom
+	c.Population = cs.Properties.Population
+	if cs.Properties.CodeDepartement != "" {
+		c.CodeDepartement = cs.Properties.CodeDepartement
+		for _, dep := range departements {
+			if dep.Code == cs.Properties.CodeDepartement {
+				c.Departement = dep.Nom
+			}
+		}
+	}
+	if cs.Properties.CodeRegion != "" {
+		for _, reg := range regions {
+			if reg.Code == cs.Properties.CodeRegion {
+				c.Region = reg.Nom
+			}
+		}
+	}
+	if len(cs.Properties.CodesPostaux) > 0 {
+		c.CodesPostaux = cs.Properties.CodesPostaux
+	}
+	if len(cs.Geometry.Coordinates) > 0 {
+		c.Location.Type = "Point"
+		c.Location.Coordinates = append(c.Location.Coordinates, cs.Geometry.Coordinates[1])
+		c.Location.Coordinates = append(c.Location.Coordinates, cs.Geometry.Coordinates[0])
+	}
+
+	raw, err := json.Marshal(c)
+	if err == nil {
+		g.save(codeInsee, cache.Entry{
+			Commune:      raw,
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+			FetchedAt:    time.Now(),
+		})
+	}
+	communeCh <- c
+}
+
+// fail records codeInsee as failed in the manifest, and only forwards err to
+// errorCh (and from there to log-errors.txt) when it is permanent: a
+// transient failure has already exhausted httpclient's retries and will get
+// another chance on the next --resume run instead of being logged as noise.
+func (g *GeoAPIGouvFr) fail(codeInsee string, err error, errorCh chan<- error) {
+	g.markFailed(codeInsee)
+	if httpclient.IsPermanent(err) {
+		errorCh <- fmt.Errorf("%s: %w", codeInsee, err)
+	}
+}
+
+// mustLoad loads codeInsee from the cache, treating a read error the same
+// as a cache miss: the commune just gets re-fetched from upstream.
+func mustLoad(store *cache.Store, codeInsee string) (cache.Entry, bool) {
+	entry, ok, err := store.Load(codeInsee)
+	if err != nil {
+		return cache.Entry{}, false
+	}
+	return entry, ok
+}
+
+func decodeCommune(entry cache.Entry) (Commune, error) {
+	var c Commune
+	err := json.Unmarshal(entry.Commune, &c)
+	return c, err
+}
+
+func (g *GeoAPIGouvFr) save(codeInsee string, entry cache.Entry) {
+	if g.Cache != nil {
+		g.Cache.Save(codeInsee, entry)
+	}
+	if g.Manifest != nil {
+		g.Manifest.MarkDone(codeInsee)
+	}
+}
+
+func (g *GeoAPIGouvFr) markFailed(codeInsee string) {
+	if g.Manifest != nil {
+		g.Manifest.MarkFailed(codeInsee)
+	}
+}
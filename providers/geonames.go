@@ -0,0 +1,190 @@
+package providers
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// GeoNames enriches communes from the GeoNames "cities15000" export, the way
+// datetime.link does it: https://download.geonames.org/export/dump/.
+//
+// GeoNames does not carry the INSEE code, only a geonameid and an admin1/2/3
+// hierarchy, so this provider cannot key its records by code INSEE like
+// GeoAPIGouvFr does. Instead it keys them by a normalized commune name and
+// Merge falls back to that name index when no INSEE match is found. This is
+// a best-effort join: homonymous communes in different departements can
+// collide and will silently take whichever GeoNames row was read last.
+type GeoNames struct {
+	// CitiesPath points to cities15000.txt (or any of the GeoNames
+	// cities*.txt dumps, they share the same column layout).
+	CitiesPath string
+	// Admin1Path points to admin1CodesASCII.txt, used to resolve the
+	// admin1 code on each city row to a region name.
+	Admin1Path string
+	// CountryPath points to countryInfo.txt, used to validate CountryCode
+	// against the ISO codes GeoNames actually knows about. Optional: if
+	// empty, CountryCode is used unchecked.
+	CountryPath string
+	// CountryCode restricts the import to a single GeoNames country code.
+	// Defaults to "FR" when empty.
+	CountryCode string
+}
+
+func (g *GeoNames) Name() string {
+	return "geonames"
+}
+
+func (g *GeoNames) Fetch(ctx context.Context) (map[string]Commune, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	countryCode := g.CountryCode
+	if countryCode == "" {
+		countryCode = "FR"
+	}
+
+	admin1Names, err := g.readAdmin1Names()
+	if err != nil {
+		return nil, err
+	}
+	countryCodes, err := g.readCountryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if len(countryCodes) > 0 && !countryCodes[countryCode] {
+		return nil, fmt.Errorf("providers: geonames country code %q not found in %s", countryCode, g.CountryPath)
+	}
+
+	file, err := os.Open(g.CitiesPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	communes := map[string]Commune{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+	for scanner.Scan() {
+		cols := strings.Split(scanner.Text(), "\t")
+		if len(cols) < 18 {
+			continue
+		}
+		if cols[8] != countryCode {
+			continue
+		}
+
+		c := Commune{Nom: cols[1]}
+		if alt := strings.TrimSpace(cols[3]); alt != "" {
+			c.AlternateNames = strings.Split(alt, ",")
+		}
+		if population, err := strconv.Atoi(cols[14]); err == nil {
+			c.Population = population
+		}
+		c.Elevation = parseElevation(cols[15], cols[16])
+		c.Timezone = cols[17]
+		c.Region = admin1Names[cols[8]+"."+cols[10]]
+
+		communes[NormalizeName(c.Nom)] = c
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return communes, nil
+}
+
+// parseElevation prefers the surveyed "elevation" column and falls back to
+// the SRTM-derived "dem" column, since GeoNames leaves elevation blank for
+// most rows.
+func parseElevation(elevation, dem string) int {
+	if v, err := strconv.Atoi(elevation); err == nil {
+		return v
+	}
+	if v, err := strconv.Atoi(dem); err == nil && v != -9999 {
+		return v
+	}
+	return 0
+}
+
+// readCountryCodes reads countryInfo.txt into the set of ISO country codes
+// it lists, so Fetch can catch a mistyped --geonames-country before it
+// silently matches zero rows from CitiesPath. Returns an empty set (no
+// restriction) when CountryPath is unset.
+func (g *GeoNames) readCountryCodes() (map[string]bool, error) {
+	codes := map[string]bool{}
+	if g.CountryPath == "" {
+		return codes, nil
+	}
+	file, err := os.Open(g.CountryPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		cols := strings.Split(line, "\t")
+		if len(cols) < 1 || cols[0] == "" {
+			continue
+		}
+		codes[cols[0]] = true
+	}
+	return codes, scanner.Err()
+}
+
+func (g *GeoNames) readAdmin1Names() (map[string]string, error) {
+	names := map[string]string{}
+	if g.Admin1Path == "" {
+		return names, nil
+	}
+	file, err := os.Open(g.Admin1Path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		cols := strings.Split(scanner.Text(), "\t")
+		if len(cols) < 2 {
+			continue
+		}
+		names[cols[0]] = cols[1]
+	}
+	return names, scanner.Err()
+}
+
+// NormalizeName folds a commune name down to a stable join key: lowercased,
+// with the accents and punctuation GeoNames and geo.api.gouv.fr disagree on
+// stripped out.
+func NormalizeName(nom string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(nom) {
+		switch r {
+		case 'à', 'â', 'ä':
+			b.WriteRune('a')
+		case 'é', 'è', 'ê', 'ë':
+			b.WriteRune('e')
+		case 'î', 'ï':
+			b.WriteRune('i')
+		case 'ô', 'ö':
+			b.WriteRune('o')
+		case 'ù', 'û', 'ü':
+			b.WriteRune('u')
+		case 'ç':
+			b.WriteRune('c')
+		case '-', '\'', ' ':
+			// dropped so "Saint-Étienne" and "Saint Etienne" collide
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,120 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+)
+
+// Merge runs every provider in order and folds their results into a single
+// map keyed by code INSEE. The first provider in order wins ties: later
+// providers only fill in fields the earlier ones left at their zero value,
+// which is what lets GeoNames enrich a GeoAPIGouvFr record (population,
+// elevation, timezone, alternate names) without overwriting the
+// authoritative nom/departement/region it already carries.
+//
+// Providers that cannot key by code INSEE (GeoNames today) are joined by
+// normalized commune name instead. That join always runs after every
+// INSEE-keyed record from every provider has been merged in, regardless of
+// --providers order, so a name-only provider listed ahead of its
+// INSEE-keyed source does not silently lose every record against a
+// half-built name index.
+func Merge(ctx context.Context, order []Provider) (map[string]Commune, []error) {
+	communes := map[string]Commune{}
+	nameIndex := map[string]string{}
+	var errs []error
+
+	type fetchResult struct {
+		provider Provider
+		records  map[string]Commune
+	}
+	var fetched []fetchResult
+
+	for _, provider := range order {
+		if ctx.Err() != nil {
+			errs = append(errs, ctx.Err())
+			break
+		}
+		records, err := provider.Fetch(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", provider.Name(), err))
+			continue
+		}
+		fetched = append(fetched, fetchResult{provider, records})
+	}
+
+	for _, f := range fetched {
+		for codeInsee, record := range f.records {
+			if record.CodeInsee == "" {
+				continue // name-only record; joined in the pass below
+			}
+			mergeRecord(communes, nameIndex, codeInsee, record)
+		}
+	}
+
+	for _, f := range fetched {
+		for _, record := range f.records {
+			if record.CodeInsee != "" {
+				continue
+			}
+			codeInsee, ok := nameIndex[NormalizeName(record.Nom)]
+			if !ok {
+				// Unknown commune for this data source (e.g. a GeoNames
+				// city outside our INSEE universe, or no INSEE-keyed
+				// provider was configured at all): nothing to merge into.
+				continue
+			}
+			mergeRecord(communes, nameIndex, codeInsee, record)
+		}
+	}
+
+	return communes, errs
+}
+
+// mergeRecord folds record into communes[codeInsee]: the first record seen
+// for a code becomes the entry outright, every later one only fills in
+// fields that entry left at its zero value.
+func mergeRecord(communes map[string]Commune, nameIndex map[string]string, codeInsee string, record Commune) {
+	existing, ok := communes[codeInsee]
+	if !ok {
+		record.CodeInsee = codeInsee
+		communes[codeInsee] = record
+		nameIndex[NormalizeName(record.Nom)] = codeInsee
+		return
+	}
+	mergeInto(&existing, record)
+	communes[codeInsee] = existing
+}
+
+// mergeInto copies every field src sets that dst left at its zero value.
+func mergeInto(dst *Commune, src Commune) {
+	if dst.Nom == "" {
+		dst.Nom = src.Nom
+	}
+	if dst.Departement == "" {
+		dst.Departement = src.Departement
+	}
+	if dst.CodeDepartement == "" {
+		dst.CodeDepartement = src.CodeDepartement
+	}
+	if dst.Region == "" {
+		dst.Region = src.Region
+	}
+	if len(dst.CodesPostaux) == 0 {
+		dst.CodesPostaux = src.CodesPostaux
+	}
+	if dst.Location.Type == "" {
+		dst.Location = src.Location
+	}
+	if dst.Population == 0 {
+		dst.Population = src.Population
+	}
+	if dst.Elevation == 0 {
+		dst.Elevation = src.Elevation
+	}
+	if dst.Timezone == "" {
+		dst.Timezone = src.Timezone
+	}
+	if len(dst.AlternateNames) == 0 {
+		dst.AlternateNames = src.AlternateNames
+	}
+}
@@ -0,0 +1,136 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type fakeProvider struct {
+	name    string
+	records map[string]Commune
+	err     error
+}
+
+func (f *fakeProvider) Name() string { return f.name }
+
+func (f *fakeProvider) Fetch(ctx context.Context) (map[string]Commune, error) {
+	return f.records, f.err
+}
+
+func TestMergeFirstProviderWinsTies(t *testing.T) {
+	first := &fakeProvider{name: "geoapi", records: map[string]Commune{
+		"75056": {CodeInsee: "75056", Nom: "Paris", Departement: "Paris", CodesPostaux: []string{"75001"}},
+	}}
+	second := &fakeProvider{name: "geonames", records: map[string]Commune{
+		"75056": {CodeInsee: "75056", Nom: "Should not win", Departement: "Should not win", Population: 2000000, Timezone: "Europe/Paris"},
+	}}
+
+	communes, errs := Merge(context.Background(), []Provider{first, second})
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	got, ok := communes["75056"]
+	if !ok {
+		t.Fatal("expected commune 75056 in merged result")
+	}
+	if got.Nom != "Paris" || got.Departement != "Paris" {
+		t.Errorf("first provider's nom/departement should win ties, got %+v", got)
+	}
+	if got.Population != 2000000 || got.Timezone != "Europe/Paris" {
+		t.Errorf("second provider should have filled zero-value fields, got %+v", got)
+	}
+}
+
+func TestMergeNameIndexFallbackJoin(t *testing.T) {
+	geoapi := &fakeProvider{name: "geoapi", records: map[string]Commune{
+		"01001": {CodeInsee: "01001", Nom: "L'Abergement-Clémenciat", Departement: "Ain"},
+	}}
+	geonames := &fakeProvider{name: "geonames", records: map[string]Commune{
+		NormalizeName("L'Abergement-Clémenciat"): {Nom: "L'Abergement-Clémenciat", Population: 900},
+	}}
+
+	communes, errs := Merge(context.Background(), []Provider{geoapi, geonames})
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	got, ok := communes["01001"]
+	if !ok {
+		t.Fatal("expected the geonames record to join onto 01001 by normalized name")
+	}
+	if got.Population != 900 {
+		t.Errorf("expected population enriched via name-index join, got %+v", got)
+	}
+}
+
+func TestMergeNameIndexFallbackJoinIsOrderIndependent(t *testing.T) {
+	geoapi := &fakeProvider{name: "geoapi", records: map[string]Commune{
+		"01001": {CodeInsee: "01001", Nom: "L'Abergement-Clémenciat", Departement: "Ain"},
+	}}
+	geonames := &fakeProvider{name: "geonames", records: map[string]Commune{
+		NormalizeName("L'Abergement-Clémenciat"): {Nom: "L'Abergement-Clémenciat", Population: 900},
+	}}
+
+	// geonames listed ahead of geoapi used to lose this record entirely,
+	// because the name index was only as complete as whatever had already
+	// been iterated when this record was processed.
+	communes, errs := Merge(context.Background(), []Provider{geonames, geoapi})
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	got, ok := communes["01001"]
+	if !ok {
+		t.Fatal("expected the geonames record to join onto 01001 regardless of provider order")
+	}
+	if got.Population != 900 {
+		t.Errorf("expected population enriched via name-index join, got %+v", got)
+	}
+}
+
+func TestMergeUnmatchedNameIsDropped(t *testing.T) {
+	geonames := &fakeProvider{name: "geonames", records: map[string]Commune{
+		NormalizeName("Nowhereville"): {Nom: "Nowhereville"},
+	}}
+
+	communes, errs := Merge(context.Background(), []Provider{geonames})
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if len(communes) != 0 {
+		t.Errorf("expected no communes merged for an unmatched name, got %+v", communes)
+	}
+}
+
+func TestMergeCollectsProviderErrors(t *testing.T) {
+	failing := &fakeProvider{name: "geoapi", err: errors.New("boom")}
+	_, errs := Merge(context.Background(), []Provider{failing})
+	if len(errs) != 1 {
+		t.Fatalf("expected 1 error, got %v", errs)
+	}
+}
+
+func TestMergeInto(t *testing.T) {
+	dst := Commune{Nom: "Paris", Departement: "Paris"}
+	src := Commune{
+		Nom:             "Should not overwrite",
+		Departement:     "Should not overwrite",
+		CodeDepartement: "75",
+		Region:          "Île-de-France",
+		CodesPostaux:    []string{"75001"},
+		Population:      2000000,
+	}
+	mergeInto(&dst, src)
+
+	want := Commune{
+		Nom:             "Paris",
+		Departement:     "Paris",
+		CodeDepartement: "75",
+		Region:          "Île-de-France",
+		CodesPostaux:    []string{"75001"},
+		Population:      2000000,
+	}
+	if !reflect.DeepEqual(dst, want) {
+		t.Errorf("mergeInto() = %+v, want %+v", dst, want)
+	}
+}
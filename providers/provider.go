@@ -0,0 +1,39 @@
+// Package providers defines the CommuneProvider interface and the unified
+// Commune record produced by merging one or more data sources.
+package providers
+
+import "context"
+
+// Location is a GeoJSON-style point, kept identical to the geo.api.gouv.fr
+// "centre" geometry so it can be re-emitted as-is.
+type Location struct {
+	Type        string    `json:"type"`
+	Coordinates []float64 `json:"coordinates"`
+}
+
+// Commune is the record shared across every provider and across the whole
+// pipeline. Fields are additive: a provider that has nothing to say about a
+// field should simply leave it at its zero value rather than overwrite it.
+type Commune struct {
+	CodeInsee       string   `json:"codeInsee"`
+	Nom             string   `json:"nom"`
+	Departement     string   `json:"departement,omitempty"`
+	CodeDepartement string   `json:"codeDepartement,omitempty"`
+	Region          string   `json:"region,omitempty"`
+	CodesPostaux    []string `json:"codesPostaux"`
+	Location        Location `json:"location"`
+	Population      int      `json:"population,omitempty"`
+	Elevation       int      `json:"elevation,omitempty"`
+	Timezone        string   `json:"timezone,omitempty"`
+	AlternateNames  []string `json:"alternateNames,omitempty"`
+}
+
+// Provider fetches commune records from a single data source.
+type Provider interface {
+	// Name identifies the provider in --providers and in log output.
+	Name() string
+	// Fetch returns every commune known to this provider, keyed by code
+	// INSEE. It must return promptly once ctx is done, so a Ctrl+C drains
+	// in-flight work instead of piling up more of it.
+	Fetch(ctx context.Context) (map[string]Commune, error)
+}